@@ -4,10 +4,22 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 )
 
-// ErrBadRawCommand is an error returned by ParseCommand in case the command data is not long enough.
-var ErrBadRawCommand = errors.New("command must be at least 4 bytes")
+// ErrBadRawCommand is an error returned by ParseCommand in case the command is shorter than 4 bytes
+// or its length doesn't match Lc.
+var ErrBadRawCommand = errors.New("command must be at least 4 bytes, with Lc matching the data length")
+
+// MaxDataLength is the longest data a short APDU can carry, since Lc is a single byte.
+// Commands with more data must be split with Chain.
+const MaxDataLength = 255
+
+// ErrDataTooLong is returned by Serialize when the command data doesn't fit in Lc.
+var ErrDataTooLong = errors.New("command data too long")
+
+// ClaChaining is the CLA bit set on all the commands of a chain except the last one.
+const ClaChaining = 0x10
 
 // Command struct represent the data sent as an APDU command with CLA, Ins, P1, P2, Lc, Data, and Le.
 type Command struct {
@@ -21,6 +33,7 @@ type Command struct {
 }
 
 // NewCommand returns a new apdu Command.
+// data is checked when the command is serialized, and must not be longer than MaxDataLength.
 func NewCommand(cla, ins, p1, p2 uint8, data []byte) *Command {
 	return &Command{
 		Cla:        cla,
@@ -43,8 +56,27 @@ func (c *Command) Le() (bool, uint8) {
 	return c.requiresLe, c.le
 }
 
+// String returns the command fields in hex, for logging. Lc and Le are omitted when not sent.
+func (c *Command) String() string {
+	s := fmt.Sprintf("CLA=%02X INS=%02X P1=%02X P2=%02X", c.Cla, c.Ins, c.P1, c.P2)
+	if len(c.Data) > 0 {
+		s += fmt.Sprintf(" Lc=%02X Data=%X", len(c.Data), c.Data)
+	}
+
+	if c.requiresLe {
+		s += fmt.Sprintf(" Le=%02X", c.le)
+	}
+
+	return s
+}
+
 // Serialize serielizes the command into a raw bytes sequence.
+// It returns ErrDataTooLong if the data is longer than MaxDataLength.
 func (c *Command) Serialize() ([]byte, error) {
+	if len(c.Data) > MaxDataLength {
+		return nil, fmt.Errorf("%w: %d bytes, at most %d can be sent without chaining", ErrDataTooLong, len(c.Data), MaxDataLength)
+	}
+
 	buf := new(bytes.Buffer)
 
 	if err := binary.Write(buf, binary.BigEndian, c.Cla); err != nil {
@@ -81,45 +113,65 @@ func (c *Command) Serialize() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func (c *Command) deserialize(data []byte) error {
-	if len(data) < 4 {
-		return ErrBadRawCommand
+// Chain splits the command data in chunks of at most size bytes and returns a command for each chunk.
+// All commands except the last have the ClaChaining bit set, and only the last one has Le.
+// A command with no more than size bytes of data is returned as is.
+func (c *Command) Chain(size int) []*Command {
+	if len(c.Data) <= size {
+		return []*Command{c}
 	}
 
-	buf := bytes.NewReader(data)
+	cmds := make([]*Command, 0, (len(c.Data)+size-1)/size)
+	for offset := 0; offset < len(c.Data); offset += size {
+		end := offset + size
+		cla := c.Cla | ClaChaining
+		if end >= len(c.Data) {
+			end = len(c.Data)
+			cla = c.Cla
+		}
 
-	if err := binary.Read(buf, binary.BigEndian, &c.Cla); err != nil {
-		return err
-	}
+		cmd := NewCommand(cla, c.Ins, c.P1, c.P2, c.Data[offset:end])
+		if end == len(c.Data) && c.requiresLe {
+			cmd.SetLe(c.le)
+		}
 
-	if err := binary.Read(buf, binary.BigEndian, &c.Ins); err != nil {
-		return err
+		cmds = append(cmds, cmd)
 	}
 
-	if err := binary.Read(buf, binary.BigEndian, &c.P1); err != nil {
-		return err
-	}
+	return cmds
+}
 
-	if err := binary.Read(buf, binary.BigEndian, &c.P2); err != nil {
-		return err
+// deserialize parses the four ISO 7816-4 short cases: header only, header and Le,
+// header with Lc and data, and header with Lc, data and Le.
+func (c *Command) deserialize(data []byte) error {
+	if len(data) < 4 {
+		return ErrBadRawCommand
 	}
 
-	var lc uint8
-	if err := binary.Read(buf, binary.BigEndian, &lc); err != nil {
+	c.Cla = data[0]
+	c.Ins = data[1]
+	c.P1 = data[2]
+	c.P2 = data[3]
+	body := data[4:]
+
+	switch {
+	case len(body) == 0:
+		return nil
+	case len(body) == 1:
+		c.SetLe(body[0])
 		return nil
 	}
 
-	cmdData := make([]byte, lc)
-	if err := binary.Read(buf, binary.BigEndian, &cmdData); err != nil {
-		return nil
+	lc := int(body[0])
+	body = body[1:]
+	if lc == 0 || len(body) < lc || len(body) > lc+1 {
+		return ErrBadRawCommand
 	}
-	c.Data = cmdData
 
-	var le uint8
-	if err := binary.Read(buf, binary.BigEndian, &le); err != nil {
-		return nil
+	c.Data = append([]byte{}, body[:lc]...)
+	if len(body) == lc+1 {
+		c.SetLe(body[lc])
 	}
-	c.SetLe(le)
 
 	return nil
 }
@@ -41,3 +41,80 @@ func TestParseCommand(t *testing.T) {
 	assert.True(t, cmd.requiresLe)
 	assert.Equal(t, uint8(0x07), cmd.le)
 }
+
+func TestParseCommand_RoundTrip(t *testing.T) {
+	for _, raw := range []string{
+		"00 A4 04 00",             // case 1
+		"00 B0 00 00 0F",          // case 2
+		"80 F2 00 01 02 8F 00",    // case 3
+		"00 A4 04 00 02 A0 00 00", // case 4
+		"00 C0 00 00 00",          // case 2 with Le 0
+		"80 E2 00 00 01 FF FF",    // case 4 with Le 0xFF
+	} {
+		cmd, err := ParseCommand(hexutils.HexToBytes(raw))
+		require.NoError(t, err, raw)
+		serialized, err := cmd.Serialize()
+		require.NoError(t, err, raw)
+		assert.Equal(t, raw, hexutils.BytesToHexWithSpaces(serialized))
+	}
+
+	cmd, err := ParseCommand(hexutils.HexToBytes("00B000000F"))
+	require.NoError(t, err)
+	hasLe, le := cmd.Le()
+	assert.True(t, hasLe)
+	assert.Equal(t, uint8(0x0F), le)
+	assert.Empty(t, cmd.Data)
+
+	for _, raw := range []string{"00 A4 04", "00 A4 04 00 03 A0 00", "00 A4 04 00 01 A0 00 00", "00 A4 04 00 00 00"} {
+		_, err = ParseCommand(hexutils.HexToBytes(raw))
+		assert.Equal(t, ErrBadRawCommand, err, raw)
+	}
+}
+
+func TestCommand_Chain(t *testing.T) {
+	cmd := NewCommand(0x80, 0xE2, 0x01, 0x02, hexutils.HexToBytes("0102030405"))
+	cmd.SetLe(0)
+
+	cmds := cmd.Chain(5)
+	assert.Equal(t, []*Command{cmd}, cmds)
+
+	cmds = cmd.Chain(2)
+	require.Len(t, cmds, 3)
+
+	expected := []string{
+		"90 E2 01 02 02 01 02",
+		"90 E2 01 02 02 03 04",
+		"80 E2 01 02 01 05 00",
+	}
+
+	for i, c := range cmds {
+		raw, err := c.Serialize()
+		require.NoError(t, err)
+		assert.Equal(t, expected[i], hexutils.BytesToHexWithSpaces(raw))
+	}
+}
+
+func TestCommand_SerializeDataTooLong(t *testing.T) {
+	cmd := NewCommand(0x00, 0xA4, 0x04, 0x00, make([]byte, MaxDataLength))
+	raw, err := cmd.Serialize()
+	require.NoError(t, err)
+	assert.Equal(t, byte(0xFF), raw[4])
+
+	cmd = NewCommand(0x00, 0xA4, 0x04, 0x00, make([]byte, MaxDataLength+1))
+	_, err = cmd.Serialize()
+	assert.ErrorIs(t, err, ErrDataTooLong)
+
+	for _, c := range cmd.Chain(MaxDataLength) {
+		_, err = c.Serialize()
+		assert.NoError(t, err)
+	}
+}
+
+func TestCommand_String(t *testing.T) {
+	cmd := NewCommand(0x80, 0xF2, 0x00, 0x01, nil)
+	assert.Equal(t, "CLA=80 INS=F2 P1=00 P2=01", cmd.String())
+
+	cmd = NewCommand(0x00, 0xA4, 0x04, 0x00, []byte{0xA0, 0x00})
+	cmd.SetLe(0)
+	assert.Equal(t, "CLA=00 INS=A4 P1=04 P2=00 Lc=02 Data=A000 Le=00", cmd.String())
+}
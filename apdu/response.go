@@ -28,7 +28,12 @@ func NewErrBadResponse(sw uint16, message string) *ErrBadResponse {
 
 // Error implements the error interface.
 func (e *ErrBadResponse) Error() string {
-	return fmt.Sprintf("bad response %x: %s", e.Sw, e.message)
+	return fmt.Sprintf("%s: %s", e.message, SwToString(e.Sw))
+}
+
+// Code returns the Sw code of the response.
+func (e *ErrBadResponse) Code() uint16 {
+	return e.Sw
 }
 
 // Response represents a struct containing the smartcard response fields.
@@ -77,3 +82,12 @@ func (r *Response) deserialize(data []byte) error {
 func (r *Response) IsOK() bool {
 	return r.Sw == SwOK
 }
+
+// String returns the response data in hex and the status word, for logging.
+func (r *Response) String() string {
+	if len(r.Data) == 0 {
+		return fmt.Sprintf("SW=%04X", r.Sw)
+	}
+
+	return fmt.Sprintf("Data=%X SW=%04X", r.Data, r.Sw)
+}
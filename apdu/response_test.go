@@ -5,6 +5,7 @@ import (
 
 	"github.com/status-im/keycard-go/hexutils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseResponse(t *testing.T) {
@@ -32,3 +33,13 @@ func TestResp_IsOK(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, resp.IsOK())
 }
+
+func TestResponse_String(t *testing.T) {
+	resp, err := ParseResponse([]byte{0x01, 0x02, 0x90, 0x00})
+	require.NoError(t, err)
+	assert.Equal(t, "Data=0102 SW=9000", resp.String())
+
+	resp, err = ParseResponse([]byte{0x6A, 0x82})
+	require.NoError(t, err)
+	assert.Equal(t, "SW=6A82", resp.String())
+}
@@ -0,0 +1,43 @@
+package apdu
+
+import "fmt"
+
+var statusWords = map[uint16]string{
+	SwOK:   "success",
+	0x6283: "selected file invalidated",
+	0x6581: "memory failure",
+	0x6700: "wrong length",
+	0x6982: "security status not satisfied",
+	0x6983: "authentication method blocked",
+	0x6984: "referenced data invalidated",
+	0x6985: "conditions of use not satisfied",
+	0x6A80: "incorrect parameters in the data field",
+	0x6A82: "file or application not found",
+	0x6A84: "not enough memory space",
+	0x6A86: "incorrect parameters P1-P2",
+	0x6A88: "referenced data not found",
+	0x6B00: "wrong parameters P1-P2",
+	0x6D00: "instruction not supported",
+	0x6E00: "class not supported",
+	0x6F00: "unknown error",
+}
+
+// SwToString returns a description of the ISO 7816 status word sw, followed by its hex value.
+func SwToString(sw uint16) string {
+	if desc, ok := statusWords[sw]; ok {
+		return fmt.Sprintf("%s (%04X)", desc, sw)
+	}
+
+	switch sw & 0xFF00 {
+	case 0x6100:
+		return fmt.Sprintf("%d more bytes available (%04X)", sw&0xFF, sw)
+	case 0x6300:
+		if sw&0xFFF0 == 0x63C0 {
+			return fmt.Sprintf("verification failed, %d attempts left (%04X)", sw&0x0F, sw)
+		}
+	case 0x6C00:
+		return fmt.Sprintf("wrong length, %d bytes expected (%04X)", sw&0xFF, sw)
+	}
+
+	return fmt.Sprintf("unknown status (%04X)", sw)
+}
@@ -0,0 +1,22 @@
+package apdu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSwToString(t *testing.T) {
+	assert.Equal(t, "success (9000)", SwToString(0x9000))
+	assert.Equal(t, "conditions of use not satisfied (6985)", SwToString(0x6985))
+	assert.Equal(t, "instruction not supported (6D00)", SwToString(0x6D00))
+	assert.Equal(t, "verification failed, 2 attempts left (63C2)", SwToString(0x63C2))
+	assert.Equal(t, "16 more bytes available (6110)", SwToString(0x6110))
+	assert.Equal(t, "unknown status (6301)", SwToString(0x6301))
+	assert.Equal(t, "unknown status (1234)", SwToString(0x1234))
+}
+
+func TestErrBadResponse_Error(t *testing.T) {
+	err := NewErrBadResponse(0x6985, "unexpected response")
+	assert.Equal(t, "unexpected response: conditions of use not satisfied (6985)", err.Error())
+}
@@ -13,6 +13,7 @@ type Tag []byte
 var (
 	ErrUnsupportedLenth80 = errors.New("length cannot be 0x80")
 	ErrLengthTooBig       = errors.New("length cannot be more than 3 bytes")
+	ErrMalformedTLV       = errors.New("malformed TLV")
 )
 
 // ErrTagNotFound is an error returned if a tag is not found in a TLV sequence.
@@ -25,7 +26,16 @@ func (e *ErrTagNotFound) Error() string {
 	return fmt.Sprintf("tag %x not found", e.tag)
 }
 
+// IsTagNotFound returns true if err is an ErrTagNotFound, so that optional tags can be told apart
+// from TLV sequences returning ErrMalformedTLV.
+func IsTagNotFound(err error) bool {
+	var notFound *ErrTagNotFound
+	return errors.As(err, &notFound)
+}
+
 // FindTag searches for a tag value within a TLV sequence.
+// It returns an ErrTagNotFound if the sequence doesn't contain the tag,
+// and an error wrapping ErrMalformedTLV if it's truncated or has invalid lengths.
 func FindTag(raw []byte, tags ...Tag) ([]byte, error) {
 	return findTag(raw, 0, tags...)
 }
@@ -44,13 +54,13 @@ func findTag(raw []byte, occurrence int, tags ...Tag) ([]byte, error) {
 	buf := bytes.NewBuffer(raw)
 
 	var (
-		tag    Tag
-		length uint32
-		err    error
+		tag  Tag
+		data []byte
+		err  error
 	)
 
 	for {
-		tag, err = parseTag(buf)
+		tag, data, err = parseTLV(buf)
 		switch {
 		case err == io.EOF:
 			return []byte{}, &ErrTagNotFound{target}
@@ -58,19 +68,6 @@ func findTag(raw []byte, occurrence int, tags ...Tag) ([]byte, error) {
 			return nil, err
 		}
 
-		length, err = ParseLength(buf)
-		if err != nil {
-			return nil, err
-		}
-
-		data := make([]byte, length)
-		if length != 0 {
-			_, err = buf.Read(data)
-			if err != nil {
-				return nil, err
-			}
-		}
-
 		if bytes.Equal(tag, target) {
 			// if it's the last tag in the search path, we start counting the occurrences
 			if len(tags) == 1 && occurrence > 0 {
@@ -87,6 +84,57 @@ func findTag(raw []byte, occurrence int, tags ...Tag) ([]byte, error) {
 	}
 }
 
+// EachTag calls fn with the tag and value of each TLV directly inside the template found
+// following tags, or inside raw if no tags are given. It stops as soon as fn returns false.
+// Unlike FindTag, it lets callers parse templates without knowing all their tags in advance.
+func EachTag(raw []byte, fn func(tag Tag, value []byte) bool, tags ...Tag) error {
+	template, err := FindTag(raw, tags...)
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(template)
+	for {
+		tag, data, err := parseTLV(buf)
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if !fn(tag, data) {
+			return nil
+		}
+	}
+}
+
+// parseTLV returns io.EOF only if buf is empty, any other error wraps ErrMalformedTLV.
+func parseTLV(buf *bytes.Buffer) (Tag, []byte, error) {
+	if buf.Len() == 0 {
+		return nil, nil, io.EOF
+	}
+
+	tag, err := parseTag(buf)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: truncated tag", ErrMalformedTLV)
+	}
+
+	length, err := ParseLength(buf)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: tag %x length: %v", ErrMalformedTLV, tag, err)
+	}
+
+	if uint32(buf.Len()) < length {
+		return nil, nil, fmt.Errorf("%w: tag %x has %d bytes, %d left", ErrMalformedTLV, tag, length, buf.Len())
+	}
+
+	data := make([]byte, length)
+	copy(data, buf.Next(int(length)))
+
+	return tag, data, nil
+}
+
 func ParseLength(buf *bytes.Buffer) (uint32, error) {
 	length, err := buf.ReadByte()
 	if err != nil {
@@ -104,9 +152,9 @@ func ParseLength(buf *bytes.Buffer) (uint32, error) {
 		}
 
 		data := make([]byte, lengthSize)
-		_, err = buf.Read(data)
+		_, err = io.ReadFull(buf, data)
 		if err != nil {
-			return 0, err
+			return 0, io.ErrUnexpectedEOF
 		}
 
 		num := make([]byte, 4)
@@ -142,6 +190,13 @@ func WriteLength(buf *bytes.Buffer, length uint32) {
 	}
 }
 
+// WriteTLV writes tag, the length of value and value to buf.
+func WriteTLV(buf *bytes.Buffer, tag Tag, value []byte) {
+	buf.Write(tag)
+	WriteLength(buf, uint32(len(value)))
+	buf.Write(value)
+}
+
 func parseTag(buf *bytes.Buffer) (Tag, error) {
 	tag := make(Tag, 0)
 	b, err := buf.ReadByte()
@@ -2,6 +2,7 @@ package apdu
 
 import (
 	"bytes"
+	"io"
 	"testing"
 
 	"github.com/status-im/keycard-go/hexutils"
@@ -42,6 +43,55 @@ func TestFindTag(t *testing.T) {
 	data = hexutils.HexToBytes("C1 02 C2 00")
 	_, err = FindTag(data, Tag{0xC1}, Tag{0xC3})
 	assert.Equal(t, &ErrTagNotFound{Tag{0xC3}}, err)
+	assert.True(t, IsTagNotFound(err))
+}
+
+func TestFindTagMalformed(t *testing.T) {
+	for _, raw := range []string{
+		"C1 04 BB CC",       // truncated value
+		"C1 02 BB CC C2",    // missing length
+		"C1 02 BB CC C2 82", // truncated long length
+		"C1 02 BB CC 9F",    // truncated multi-byte tag
+		"C1 80",             // unsupported length
+	} {
+		_, err := FindTag(hexutils.HexToBytes(raw), Tag{0xC3})
+		assert.ErrorIs(t, err, ErrMalformedTLV, raw)
+		assert.False(t, IsTagNotFound(err), raw)
+	}
+}
+
+func TestEachTag(t *testing.T) {
+	data := hexutils.HexToBytes("A4 0A C1 02 BB CC C2 00 C1 02 11 22")
+
+	var tags []Tag
+	var values [][]byte
+	err := EachTag(data, func(tag Tag, value []byte) bool {
+		tags = append(tags, tag)
+		values = append(values, value)
+		return true
+	}, Tag{0xA4})
+	require.NoError(t, err)
+	assert.Equal(t, []Tag{{0xC1}, {0xC2}, {0xC1}}, tags)
+	assert.Equal(t, [][]byte{{0xBB, 0xCC}, {}, {0x11, 0x22}}, values)
+
+	count := 0
+	err = EachTag(data, func(tag Tag, value []byte) bool {
+		count++
+		return false
+	}, Tag{0xA4})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count = 0
+	err = EachTag(data, func(tag Tag, value []byte) bool {
+		count++
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	err = EachTag(data, func(tag Tag, value []byte) bool { return true }, Tag{0xA5})
+	assert.Equal(t, &ErrTagNotFound{Tag{0xA5}}, err)
 }
 
 func TestParseLength(t *testing.T) {
@@ -85,6 +135,16 @@ func TestParseLength(t *testing.T) {
 			expectedLength: 0,
 			err:            ErrLengthTooBig,
 		},
+		{
+			data:           []byte{0x82, 0x01},
+			expectedLength: 0,
+			err:            io.ErrUnexpectedEOF,
+		},
+		{
+			data:           []byte{0x81},
+			expectedLength: 0,
+			err:            io.ErrUnexpectedEOF,
+		},
 	}
 
 	for _, s := range scenarios {
@@ -99,6 +159,53 @@ func TestParseLength(t *testing.T) {
 	}
 }
 
+func TestFindTagLongLength(t *testing.T) {
+	pubKey := bytes.Repeat([]byte{0x04}, 200)
+	chainCode := bytes.Repeat([]byte{0xCC}, 32)
+
+	// 81 LL template holding an 81 LL public key
+	inner := append([]byte{0x80, 0x81, 0xC8}, pubKey...)
+	inner = append(inner, 0x82, 0x20)
+	inner = append(inner, chainCode...)
+	data := append([]byte{0xA1, 0x81, byte(len(inner))}, inner...)
+
+	tagData, err := FindTag(data, Tag{0xA1}, Tag{0x80})
+	require.NoError(t, err)
+	assert.Equal(t, pubKey, tagData)
+
+	tagData, err = FindTag(data, Tag{0xA1}, Tag{0x82})
+	require.NoError(t, err)
+	assert.Equal(t, chainCode, tagData)
+
+	// 82 LLLL template
+	value := bytes.Repeat([]byte{0xAB}, 300)
+	data = append([]byte{0xA2, 0x82, 0x01, 0x2C}, value...)
+	data = append(data, 0xC1, 0x01, 0x55)
+
+	tagData, err = FindTag(data, Tag{0xA2})
+	require.NoError(t, err)
+	assert.Equal(t, value, tagData)
+
+	tagData, err = FindTag(data, Tag{0xC1})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x55}, tagData)
+}
+
+func TestWriteTLV(t *testing.T) {
+	buf := new(bytes.Buffer)
+	WriteTLV(buf, Tag{0x80}, []byte{0x01, 0x02})
+	WriteTLV(buf, Tag{0x5F, 0x20}, nil)
+	assert.Equal(t, "80 02 01 02 5F 20 00", hexutils.BytesToHexWithSpaces(buf.Bytes()))
+
+	value := bytes.Repeat([]byte{0xAB}, 200)
+	buf.Reset()
+	WriteTLV(buf, Tag{0xA1}, value)
+
+	tagData, err := FindTag(buf.Bytes(), Tag{0xA1})
+	require.NoError(t, err)
+	assert.Equal(t, value, tagData)
+}
+
 func TestFindTagN(t *testing.T) {
 	data := hexutils.HexToBytes("0A 01 A1 0A 01 A2")
 
@@ -7,11 +7,14 @@ import (
 	"github.com/status-im/keycard-go/types"
 )
 
+// CashCommandSet sends commands to the Keycard Cash applet, which signs with a key
+// generated on install, without pairing, PIN or secure channel.
 type CashCommandSet struct {
 	c                   types.Channel
 	CashApplicationInfo *types.CashApplicationInfo
 }
 
+// NewCashCommandSet returns a new CashCommandSet sending commands through c.
 func NewCashCommandSet(c types.Channel) *CashCommandSet {
 	return &CashCommandSet{
 		c:                   c,
@@ -19,6 +22,7 @@ func NewCashCommandSet(c types.Channel) *CashCommandSet {
 	}
 }
 
+// Select selects the Cash applet and sets CashApplicationInfo with its public key and data.
 func (cs *CashCommandSet) Select() error {
 	cmd := globalplatform.NewCommandSelect(identifiers.CashInstanceAID)
 	cmd.SetLe(0)
@@ -37,8 +41,9 @@ func (cs *CashCommandSet) Select() error {
 	return nil
 }
 
+// Sign signs the 32 bytes hash data with the Cash applet key.
 func (cs *CashCommandSet) Sign(data []byte) (*types.Signature, error) {
-	cmd, err := NewCommandSign(data, 0x00, "")
+	cmd, err := NewCommandSign(data, P1SignCurrentKey, "")
 	if err != nil {
 		return nil, err
 	}
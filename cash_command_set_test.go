@@ -0,0 +1,51 @@
+package keycard
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/globalplatform"
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/status-im/keycard-go/identifiers"
+	keycardio "github.com/status-im/keycard-go/io"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCashCommandSet_Select(t *testing.T) {
+	pubKey := append([]byte{0x04}, bytes.Repeat([]byte{0x02}, 64)...)
+	tpl := append([]byte{0x80, 0x41}, pubKey...)
+	tpl = append(tpl, hexutils.HexToBytes("82 01 AA 02 02 01 00")...)
+	resp := append([]byte{0xA4, byte(len(tpl))}, tpl...)
+
+	c := keycardio.NewMockChannel().Expect(globalplatform.InsSelect, resp, apdu.SwOK)
+	cs := NewCashCommandSet(c)
+
+	require.NoError(t, cs.Select())
+	assert.Equal(t, identifiers.CashInstanceAID, c.Sent[0].Data)
+	assert.True(t, cs.CashApplicationInfo.Installed)
+	assert.Equal(t, pubKey, cs.CashApplicationInfo.PublicKey)
+	assert.Equal(t, []byte{0xAA}, cs.CashApplicationInfo.PublicData)
+	assert.Equal(t, []byte{0x01, 0x00}, cs.CashApplicationInfo.Version)
+}
+
+func TestCashCommandSet_Sign(t *testing.T) {
+	hash := bytes.Repeat([]byte{0x01}, 32)
+	resp, sig := signResponse(t, hash)
+
+	c := keycardio.NewMockChannel().Expect(InsSign, resp, apdu.SwOK).Respond(nil, SwConditionsNotSatisfied)
+	cs := NewCashCommandSet(c)
+
+	_, err := cs.Sign(hash[:31])
+	assert.Error(t, err)
+	assert.Empty(t, c.Sent)
+
+	signature, err := cs.Sign(hash)
+	require.NoError(t, err)
+	assert.Equal(t, sig[64], signature.V())
+	assert.Equal(t, uint8(P1SignCurrentKey), c.Sent[0].P1)
+
+	_, err = cs.Sign(hash)
+	assert.Error(t, err)
+}
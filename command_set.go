@@ -2,12 +2,16 @@ package keycard
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
+	"math/big"
 
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/status-im/keycard-go/apdu"
 	"github.com/status-im/keycard-go/crypto"
 	"github.com/status-im/keycard-go/globalplatform"
@@ -16,7 +20,32 @@ import (
 )
 
 var ErrNoAvailablePairingSlots = errors.New("no available pairing slots")
+var ErrAlreadyInitialized = errors.New("card already initialized")
+var ErrInitFailed = errors.New("card not initialized after INIT")
+var ErrInvalidPairingIndex = fmt.Errorf("pairing index must be lower than %d", MaxPairingSlots)
+var ErrInvalidMaxCommandLength = fmt.Errorf("max command length must be between %d and %d", minCommandLength, MaxCommandLength)
+var ErrSecureChannelSecretNotSet = errors.New("secure channel secret not generated, select the applet first")
+var ErrMalformedResponse = errors.New("malformed response")
+var ErrPairingInfoNotSet = errors.New("pairing info not set")
+var ErrPairingInvalid = errors.New("pairing not valid on the card")
+var ErrSecureChannelNotOpen = errors.New("secure channel not open")
+var ErrFactoryResetNotSupported = errors.New("factory reset not supported")
 var ErrBadChecksumSize = errors.New("bad checksum size")
+var ErrBadMnemonicResponse = errors.New("mnemonic response must contain 2 bytes per word")
+var ErrPINBlocked = errors.New("pin blocked")
+var ErrPUKBlocked = errors.New("puk blocked")
+var ErrConditionsNotSatisfied = errors.New("conditions of use not satisfied")
+var ErrInvalidPIN = errors.New("pin must be 6 digits")
+var ErrInvalidPUK = errors.New("puk must be 12 digits")
+var ErrKeyAlreadyExists = errors.New("key already exists")
+var ErrInvalidSeedLength = errors.New("seed must be 64 bytes")
+var ErrKeyNotRemoved = errors.New("key still present after removal")
+var ErrInvalidChallengeLength = errors.New("challenge must be 32 bytes")
+var ErrInvalidPairingTokenIterations = errors.New("pairing token iterations must be positive")
+var ErrInvalidHashLength = errors.New("message hash must be 32 bytes")
+var ErrInvalidChainID = errors.New("chain id must be positive")
+var ErrPinlessPathNotSet = errors.New("pinless path not set")
+var ErrDataTooLong = fmt.Errorf("data cannot be longer than %d bytes", MaxStoreDataLength)
 
 type WrongPINError struct {
 	RemainingAttempts int
@@ -34,21 +63,79 @@ func (e *WrongPUKError) Error() string {
 	return fmt.Sprintf("wrong puk. remaining attempts: %d", e.RemainingAttempts)
 }
 
+// CommandSet sends Keycard commands to a card, keeping track of the selected application info,
+// the pairing and the secure channel session.
 type CommandSet struct {
-	c               types.Channel
-	sc              *SecureChannel
-	ApplicationInfo *types.ApplicationInfo
-	PairingInfo     *types.PairingInfo
+	c                      *contextChannel
+	sc                     *SecureChannel
+	maxCommandLength       int
+	random                 io.Reader
+	pairingTokenIterations int
+	pinlessPath            string
+	ApplicationInfo        *types.ApplicationInfo
+	PairingInfo            *types.PairingInfo
 }
 
 func NewCommandSet(c types.Channel) *CommandSet {
+	cc := newContextChannel(c)
 	return &CommandSet{
-		c:               c,
-		sc:              NewSecureChannel(c),
-		ApplicationInfo: &types.ApplicationInfo{},
+		c:                      cc,
+		sc:                     NewSecureChannel(cc),
+		maxCommandLength:       MaxCommandLength,
+		random:                 rand.Reader,
+		pairingTokenIterations: crypto.PairingTokenIterations,
+		ApplicationInfo:        &types.ApplicationInfo{},
 	}
 }
 
+// SetRandom sets the source of the challenges sent by Pair, Identify and MutuallyAuthenticate
+// and of the secure channel ephemeral key, crypto/rand by default. Tests can set a fixed source
+// to send known challenges, or to replay a recorded session.
+func (cs *CommandSet) SetRandom(r io.Reader) {
+	cs.random = r
+	cs.sc.SetRandom(r)
+}
+
+// SetMaxCommandLength sets the longest command data sent in one command, MaxCommandLength by default.
+// It's lowered for readers rejecting long commands, sending STORE DATA and LOAD KEY data
+// in more chained commands, each with less than n bytes of plain text once encrypted.
+func (cs *CommandSet) SetMaxCommandLength(n int) error {
+	if n < minCommandLength || n > MaxCommandLength {
+		return ErrInvalidMaxCommandLength
+	}
+
+	cs.maxCommandLength = n
+
+	return nil
+}
+
+// SetPairingTokenIterations sets the PBKDF2 iteration count used by Init, Pair and ChangePairingSecret
+// to derive the pairing token from the pairing password, crypto.PairingTokenIterations by default.
+// The applet only stores the token and never runs PBKDF2 itself, so the count must match the one
+// used by the client that initialized the card or last changed its pairing secret.
+func (cs *CommandSet) SetPairingTokenIterations(n int) error {
+	if n <= 0 {
+		return ErrInvalidPairingTokenIterations
+	}
+
+	cs.pairingTokenIterations = n
+
+	return nil
+}
+
+// SetContext sets the context used by all the following commands until SetContext is called again.
+// Once ctx is done, commands return its error wrapped with the instruction that was being sent.
+// A command interrupted while waiting for the card leaves the secure channel out of sync,
+// so the applet must be selected and the secure channel opened again.
+// A nil ctx restores the default background context.
+func (cs *CommandSet) SetContext(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cs.c.ctx = ctx
+}
+
 func (cs *CommandSet) SetPairingInfo(key []byte, index int) {
 	cs.PairingInfo = &types.PairingInfo{
 		Key:   key,
@@ -88,21 +175,148 @@ func (cs *CommandSet) Select() error {
 	return nil
 }
 
-func (cs *CommandSet) Init(secrets *Secrets) error {
+// Init initializes the card with secrets and returns its new InstanceUID.
+// The applet only supports INIT before it's initialized, so unless force is true the applet is
+// selected first and Init fails with ErrAlreadyInitialized if the card reports being initialized.
+// With force, the key of the last Select is used and the card itself rejects INIT if initialized,
+// still returning ErrAlreadyInitialized.
+// Secrets are validated first, see Secrets.Validate. The pairing token is derived from the
+// pairing password with the count set by SetPairingTokenIterations.
+// The applet is selected again once done, setting the InstanceUID of the card in ApplicationInfo.
+func (cs *CommandSet) Init(secrets *Secrets, force bool) ([]byte, error) {
+	if !force {
+		if err := cs.Select(); err != nil {
+			return nil, err
+		}
+
+		if cs.ApplicationInfo.Initialized {
+			return nil, ErrAlreadyInitialized
+		}
+	}
+
+	// the card can only be initialized once, invalid secrets must not get to it
+	if err := secrets.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cs.sc.PublicKey() == nil {
+		return nil, ErrSecureChannelSecretNotSet
+	}
+
+	if cs.pairingTokenIterations != crypto.PairingTokenIterations {
+		s := *secrets
+		s.pairingToken = crypto.GeneratePairingToken(secrets.PairingPass(), cs.pairingTokenIterations)
+		secrets = &s
+	}
+
 	data, err := cs.sc.OneShotEncrypt(secrets)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	init := NewCommandInit(data)
 	resp, err := cs.c.Send(init)
+	if resp != nil && resp.Sw == SwInsNotSupported {
+		return nil, ErrAlreadyInitialized
+	}
 
-	return cs.checkOK(resp, err)
+	if err = cs.checkOK(resp, err); err != nil {
+		return nil, err
+	}
+
+	if err = cs.Select(); err != nil {
+		return nil, err
+	}
+
+	return cs.ApplicationInfo.InstanceUID, nil
+}
+
+// InitializeCard provisions a new card: it generates random secrets, initializes the card
+// with them and checks that the card reports being initialized.
+// The returned secrets must be shown to the user or stored, they can't be read back from the card.
+func (cs *CommandSet) InitializeCard() (*Secrets, error) {
+	secrets, err := GenerateSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = cs.Init(secrets, false); err != nil {
+		return nil, err
+	}
+
+	if !cs.ApplicationInfo.Initialized {
+		return nil, ErrInitFailed
+	}
+
+	return secrets, nil
+}
+
+// Disconnect closes the secure channel, zeroing its keys, and closes the underlying channel
+// if it implements io.Closer.
+func (cs *CommandSet) Disconnect() error {
+	cs.sc.Close()
+
+	if closer, ok := cs.c.c.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// FactoryReset removes all keys, credentials and pairings from the card, bringing it back
+// to the pre-initialized state. It doesn't need the secure channel, so it can recover cards
+// with blocked PIN and PUK. The applet is selected again once the reset is done.
+func (cs *CommandSet) FactoryReset() error {
+	if !cs.ApplicationInfo.HasFactoryResetCapability() {
+		return ErrFactoryResetNotSupported
+	}
+
+	cmd := NewCommandFactoryReset()
+	resp, err := cs.c.Send(cmd)
+	if err = cs.checkOK(resp, err); err != nil {
+		return err
+	}
+
+	cs.PairingInfo = nil
+	cs.pinlessPath = ""
+
+	return cs.Select()
 }
 
+// Identify asks the card to sign challenge with its identity key, returning the signature
+// and the certificate that can be verified against the manufacturer CA public key.
+// A random challenge is generated if challenge is nil.
+func (cs *CommandSet) Identify(challenge []byte) (*types.Identity, error) {
+	if challenge == nil {
+		challenge = make([]byte, 32)
+		if _, err := io.ReadFull(cs.random, challenge); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(challenge) != 32 {
+		return nil, ErrInvalidChallengeLength
+	}
+
+	cmd := NewCommandIdentify(challenge)
+	resp, err := cs.sc.Send(cmd)
+	if err = cs.checkOK(resp, err); err != nil {
+		return nil, err
+	}
+
+	return types.ParseIdentity(challenge, resp.Data)
+}
+
+// Pair pairs with the card using pairingPass, setting PairingInfo.
+// It fails with ErrNoAvailablePairingSlots without sending any command
+// if the application info reports that all the pairing slots are in use.
 func (cs *CommandSet) Pair(pairingPass string) error {
+	if !cs.ApplicationInfo.HasAvailablePairingSlots() {
+		return ErrNoAvailablePairingSlots
+	}
+
 	challenge := make([]byte, 32)
-	if _, err := rand.Read(challenge); err != nil {
+	if _, err := io.ReadFull(cs.random, challenge); err != nil {
 		return err
 	}
 
@@ -116,10 +330,14 @@ func (cs *CommandSet) Pair(pairingPass string) error {
 		return err
 	}
 
+	if len(resp.Data) != 64 {
+		return crypto.ErrInvalidCardCryptogram
+	}
+
 	cardCryptogram := resp.Data[:32]
 	cardChallenge := resp.Data[32:]
 
-	secretHash, err := crypto.VerifyCryptogram(challenge, pairingPass, cardCryptogram)
+	secretHash, err := crypto.VerifyCryptogramWithIterations(challenge, pairingPass, cs.pairingTokenIterations, cardCryptogram)
 	if err != nil {
 		return err
 	}
@@ -133,6 +351,10 @@ func (cs *CommandSet) Pair(pairingPass string) error {
 		return err
 	}
 
+	if len(resp.Data) != 33 {
+		return ErrMalformedResponse
+	}
+
 	h.Reset()
 	h.Write(secretHash[:])
 	h.Write(resp.Data[1:])
@@ -145,40 +367,160 @@ func (cs *CommandSet) Pair(pairingPass string) error {
 		Index: int(pairingIndex),
 	}
 
+	if len(cs.ApplicationInfo.AvailableSlots) > 0 {
+		cs.ApplicationInfo.AvailableSlots[0]--
+	}
+
 	return nil
 }
 
+// Unpair removes the pairing at index. The secure channel must be open and the PIN verified.
 func (cs *CommandSet) Unpair(index uint8) error {
 	cmd := NewCommandUnpair(index)
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 	return cs.checkOK(resp, err)
 }
 
+// UnpairOthers removes all the pairings except the one currently in use.
+// The secure channel must be open and the PIN verified.
+// Once done, ApplicationInfo reports all the other slots as available.
+func (cs *CommandSet) UnpairOthers() error {
+	if cs.PairingInfo == nil {
+		return ErrPairingInfoNotSet
+	}
+
+	for index := 0; index < MaxPairingSlots; index++ {
+		if index == cs.PairingInfo.Index {
+			continue
+		}
+
+		if err := cs.Unpair(uint8(index)); err != nil {
+			return err
+		}
+	}
+
+	cs.ApplicationInfo.AvailableSlots = []byte{MaxPairingSlots - 1}
+
+	return nil
+}
+
+// PairingSlots returns the number of free pairing slots reported by the last Select
+// and the slot of the current pairing. The applet doesn't report which slots are in use.
+func (cs *CommandSet) PairingSlots() types.PairingSlots {
+	slots := types.PairingSlots{
+		Total:   MaxPairingSlots,
+		Free:    -1,
+		Current: -1,
+	}
+
+	if len(cs.ApplicationInfo.AvailableSlots) > 0 {
+		slots.Free = int(cs.ApplicationInfo.AvailableSlots[0])
+	}
+
+	if cs.PairingInfo != nil {
+		slots.Current = cs.PairingInfo.Index
+	}
+
+	return slots
+}
+
+// OpenSecureChannel opens a new secure channel session using PairingInfo.
+// The pairing key and index are the only persistent secrets: a stored pairing can be set
+// with SetPairingInfo after Select to reconnect to a card without pairing again.
+// It can be called again on the same CommandSet after the session is lost.
+// It fails with ErrPairingInvalid if the card has no pairing at the index, or if its pairing key
+// is different, as after a firmware upgrade or Unpair from another client.
 func (cs *CommandSet) OpenSecureChannel() error {
-	if cs.ApplicationInfo == nil {
-		return errors.New("cannot open secure channel without setting PairingInfo")
+	if cs.PairingInfo == nil {
+		return ErrPairingInfoNotSet
 	}
 
+	if cs.PairingInfo.Index < 0 || cs.PairingInfo.Index >= MaxPairingSlots {
+		return ErrInvalidPairingIndex
+	}
+
+	if cs.sc.PublicKey() == nil {
+		return ErrSecureChannelSecretNotSet
+	}
+
+	cs.sc.Reset()
+
 	cmd := NewCommandOpenSecureChannel(uint8(cs.PairingInfo.Index), cs.sc.RawPublicKey())
 	resp, err := cs.c.Send(cmd)
+	if resp != nil && resp.Sw == SwIncorrectP1P2 {
+		return ErrPairingInvalid
+	}
+
 	if err = cs.checkOK(resp, err); err != nil {
 		return err
 	}
 
-	encKey, macKey, iv := crypto.DeriveSessionKeys(cs.sc.Secret(), cs.PairingInfo.Key, resp.Data)
+	encKey, macKey, iv, err := crypto.DeriveSessionKeys(cs.sc.Secret(), cs.PairingInfo.Key, resp.Data)
+	if err != nil {
+		return err
+	}
+
 	cs.sc.Init(iv, encKey, macKey)
 
-	err = cs.mutualAuthenticate()
-	if err != nil {
+	if _, err = cs.MutuallyAuthenticate(); err != nil {
+		cs.sc.Reset()
+
+		// the card couldn't verify the MAC of the command
+		if sw, ok := responseCode(err); ok && sw == SwSecurityConditionNotSatisfied {
+			return ErrPairingInvalid
+		}
+
 		return err
 	}
 
 	return nil
 }
 
+// ReopenOrRepair opens the secure channel with PairingInfo, pairing again with pairingPass
+// if the card reports the pairing as no longer valid. The new pairing is in PairingInfo
+// and should replace the stored one.
+func (cs *CommandSet) ReopenOrRepair(pairingPass string) error {
+	err := cs.OpenSecureChannel()
+	if err != ErrPairingInvalid && err != ErrPairingInfoNotSet {
+		return err
+	}
+
+	if err = cs.Pair(pairingPass); err != nil {
+		return err
+	}
+
+	return cs.OpenSecureChannel()
+}
+
+// IsSecureChannelOpen returns true if OpenSecureChannel succeeded and the secure channel
+// wasn't closed since. Commands needing the PIN also fail once it's closed, since the card
+// forgets the PIN verification with the session.
+func (cs *CommandSet) IsSecureChannelOpen() bool {
+	return cs.sc.IsOpen()
+}
+
+// PairAndOpen pairs with the card, opens a secure channel with the new pairing
+// and verifies the PIN. The returned error names the step that failed.
+func (cs *CommandSet) PairAndOpen(pairingPass, pin string) error {
+	if err := cs.Pair(pairingPass); err != nil {
+		return fmt.Errorf("pair: %w", err)
+	}
+
+	if err := cs.OpenSecureChannel(); err != nil {
+		return fmt.Errorf("open secure channel: %w", err)
+	}
+
+	if err := cs.VerifyPIN(pin); err != nil {
+		return fmt.Errorf("verify pin: %w", err)
+	}
+
+	return nil
+}
+
+// GetStatus sends a GET STATUS command. info is either P1GetStatusApplication or P1GetStatusKeyPath.
 func (cs *CommandSet) GetStatus(info uint8) (*types.ApplicationStatus, error) {
 	cmd := NewCommandGetStatus(info)
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 	if err = cs.checkOK(resp, err); err != nil {
 		return nil, err
 	}
@@ -186,22 +528,73 @@ func (cs *CommandSet) GetStatus(info uint8) (*types.ApplicationStatus, error) {
 	return types.ParseApplicationStatus(resp.Data)
 }
 
+// GetStatusApplication returns the PIN and PUK retry counters and whether a key is loaded.
 func (cs *CommandSet) GetStatusApplication() (*types.ApplicationStatus, error) {
 	return cs.GetStatus(P1GetStatusApplication)
 }
 
+// GetStatusKeyPath returns the status with the path of the current key.
 func (cs *CommandSet) GetStatusKeyPath() (*types.ApplicationStatus, error) {
 	return cs.GetStatus(P1GetStatusKeyPath)
 }
 
+// CurrentKeyPath returns the derivation path of the current key, "m" for the master key.
+func (cs *CommandSet) CurrentKeyPath() (string, error) {
+	status, err := cs.GetStatusKeyPath()
+	if err != nil {
+		return "", err
+	}
+
+	return status.Path, nil
+}
+
+// PINRetryCount returns the remaining PIN attempts without consuming any of them.
+func (cs *CommandSet) PINRetryCount() (int, error) {
+	status, err := cs.GetStatusApplication()
+	if err != nil {
+		return 0, err
+	}
+
+	return status.PinRetryCount, nil
+}
+
+// PUKRetryCount returns the remaining PUK attempts without consuming any of them.
+func (cs *CommandSet) PUKRetryCount() (int, error) {
+	status, err := cs.GetStatusApplication()
+	if err != nil {
+		return 0, err
+	}
+
+	return status.PUKRetryCount, nil
+}
+
+// IsInitialized returns true if the last Select found an initialized card.
+func (cs *CommandSet) IsInitialized() bool {
+	return cs.ApplicationInfo.Initialized
+}
+
+// IsBlocked returns true if both the PIN and the PUK are blocked, in which case the card
+// can only be recovered with a factory reset. The secure channel must be open.
+func (cs *CommandSet) IsBlocked() (bool, error) {
+	status, err := cs.GetStatusApplication()
+	if err != nil {
+		return false, err
+	}
+
+	return status.PinRetryCount == 0 && status.PUKRetryCount == 0, nil
+}
+
 func (cs *CommandSet) VerifyPIN(pin string) error {
 	cmd := NewCommandVerifyPIN(pin)
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 	if err = cs.checkOK(resp, err); err != nil {
-		if resp != nil && ((resp.Sw & 0x63C0) == 0x63C0) {
-			remainingAttempts := resp.Sw & 0x000F
+		if remainingAttempts, ok := wrongCredentialsAttempts(resp); ok {
+			if remainingAttempts == 0 {
+				return ErrPINBlocked
+			}
+
 			return &WrongPINError{
-				RemainingAttempts: int(remainingAttempts),
+				RemainingAttempts: remainingAttempts,
 			}
 		}
 		return err
@@ -210,20 +603,52 @@ func (cs *CommandSet) VerifyPIN(pin string) error {
 	return nil
 }
 
+// ChangePIN changes the user PIN. The secure channel must be open and the current PIN verified.
 func (cs *CommandSet) ChangePIN(pin string) error {
+	if !isDigits(pin, pinLength) {
+		return ErrInvalidPIN
+	}
+
 	cmd := NewCommandChangePIN(pin)
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 	return cs.checkOK(resp, err)
 }
 
+// ChangePINChecked verifies oldPIN before changing the PIN to newPIN, so a wrong current PIN
+// fails with WrongPINError without sending CHANGE PIN. The secure channel must be open.
+func (cs *CommandSet) ChangePINChecked(oldPIN, newPIN string) error {
+	if !isDigits(newPIN, pinLength) {
+		return ErrInvalidPIN
+	}
+
+	if err := cs.VerifyPIN(oldPIN); err != nil {
+		return err
+	}
+
+	return cs.ChangePIN(newPIN)
+}
+
+// UnblockPIN resets the PIN of a blocked card to newPIN using the PUK.
+// When ErrPUKBlocked is returned the card can only be recovered with a factory reset.
 func (cs *CommandSet) UnblockPIN(puk string, newPIN string) error {
+	if !isDigits(puk, pukLength) {
+		return ErrInvalidPUK
+	}
+
+	if !isDigits(newPIN, pinLength) {
+		return ErrInvalidPIN
+	}
+
 	cmd := NewCommandUnblockPIN(puk, newPIN)
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 	if err = cs.checkOK(resp, err); err != nil {
-		if resp != nil && ((resp.Sw & 0x63C0) == 0x63C0) {
-			remainingAttempts := resp.Sw & 0x000F
+		if remainingAttempts, ok := wrongCredentialsAttempts(resp); ok {
+			if remainingAttempts == 0 {
+				return ErrPUKBlocked
+			}
+
 			return &WrongPUKError{
-				RemainingAttempts: int(remainingAttempts),
+				RemainingAttempts: remainingAttempts,
 			}
 		}
 		return err
@@ -232,49 +657,81 @@ func (cs *CommandSet) UnblockPIN(puk string, newPIN string) error {
 	return nil
 }
 
+// ChangePUK changes the PUK. The secure channel must be open and the current PIN verified.
 func (cs *CommandSet) ChangePUK(puk string) error {
+	if !isDigits(puk, pukLength) {
+		return ErrInvalidPUK
+	}
+
 	cmd := NewCommandChangePUK(puk)
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 
 	return cs.checkOK(resp, err)
 }
 
+// ChangePairingSecret changes the pairing secret to the one derived from password.
+// The secure channel must be open and the current PIN verified.
 func (cs *CommandSet) ChangePairingSecret(password string) error {
-	secret := generatePairingToken(password)
+	secret := crypto.GeneratePairingToken(password, cs.pairingTokenIterations)
 	cmd := NewCommandChangePairingSecret(secret)
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 
 	return cs.checkOK(resp, err)
 }
 
-func (cs *CommandSet) GenerateKey() ([]byte, error) {
+// GenerateKey generates a new master key on the card and returns its key UID.
+// If the card already has a key, ErrKeyAlreadyExists is returned unless force is true,
+// in which case the existing key is replaced.
+// The applet itself replaces existing keys without a specific status word, so unless force
+// is true the key status is read with GET STATUS first. Select can't be used for that check
+// since it would close the secure channel GENERATE KEY is sent through.
+func (cs *CommandSet) GenerateKey(force bool) ([]byte, error) {
+	if !force {
+		status, err := cs.GetStatusApplication()
+		if err != nil {
+			return nil, err
+		}
+
+		if status.KeyInitialized {
+			return nil, ErrKeyAlreadyExists
+		}
+	}
+
 	cmd := NewCommandGenerateKey()
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 	if err = cs.checkOK(resp, err); err != nil {
 		return nil, err
 	}
 
+	cs.ApplicationInfo.KeyUID = resp.Data
+
 	return resp.Data, nil
 }
 
+// GenerateMnemonic asks the card to generate a new BIP39 mnemonic and returns the indexes
+// of its words in the BIP39 word list. checksumSize must be between 4 and 8,
+// producing mnemonics of 12 to 24 words.
 func (cs *CommandSet) GenerateMnemonic(checksumSize int) ([]int, error) {
 	if checksumSize < 4 || checksumSize > 8 {
 		return nil, ErrBadChecksumSize
 	}
 
 	cmd := NewCommandGenerateMnemonic(byte(checksumSize))
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 	if err = cs.checkOK(resp, err); err != nil {
 		return nil, err
 	}
 
+	if len(resp.Data)%2 != 0 {
+		return nil, ErrBadMnemonicResponse
+	}
+
 	buf := bytes.NewBuffer(resp.Data)
-	indexes := make([]int, 0)
-	for {
-		var index int16
-		err := binary.Read(buf, binary.BigEndian, &index)
-		if err != nil {
-			break
+	indexes := make([]int, 0, len(resp.Data)/2)
+	for buf.Len() > 0 {
+		var index uint16
+		if err := binary.Read(buf, binary.BigEndian, &index); err != nil {
+			return nil, err
 		}
 
 		indexes = append(indexes, int(index))
@@ -283,31 +740,51 @@ func (cs *CommandSet) GenerateMnemonic(checksumSize int) ([]int, error) {
 	return indexes, nil
 }
 
-func (cs *CommandSet) RemoveKey() error {
+// RemoveKey removes the master key from the card.
+// If verify is true the applet is selected again to check that the card reports no key UID.
+// Selecting the applet closes the secure channel, so it must be opened again before sending
+// other commands.
+func (cs *CommandSet) RemoveKey(verify bool) error {
 	cmd := NewCommandRemoveKey()
-	resp, err := cs.sc.Send(cmd)
-	return cs.checkOK(resp, err)
+	resp, err := cs.sendSecure(cmd)
+	if err = cs.checkOK(resp, err); err != nil {
+		return err
+	}
+
+	cs.ApplicationInfo.KeyUID = nil
+	cs.pinlessPath = ""
+
+	if !verify {
+		return nil
+	}
+
+	if err = cs.Select(); err != nil {
+		return err
+	}
+
+	if len(cs.ApplicationInfo.KeyUID) > 0 {
+		return ErrKeyNotRemoved
+	}
+
+	return nil
 }
 
+// DeriveKey derives the key at path and makes it the current key. The path prefix sets where
+// the derivation starts: "m/" from the master key, "../" from the parent of the current key
+// and "./" or no prefix from the current key, so nearby keys don't need a full derivation.
 func (cs *CommandSet) DeriveKey(path string) error {
 	cmd, err := NewCommandDeriveKey(path)
 	if err != nil {
 		return err
 	}
 
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 	return cs.checkOK(resp, err)
 }
 
-func (cs *CommandSet) ExportKey(derive bool, makeCurrent bool, onlyPublic bool, path string) ([]byte, []byte, error) {
-	var p1 uint8
-	if !derive {
-		p1 = P1ExportKeyCurrent
-	} else if !makeCurrent {
-		p1 = P1ExportKeyDerive
-	} else {
-		p1 = P1ExportKeyDeriveAndMakeCurrent
-	}
+// ExportKey exports the current key, or the key at path if derive is true.
+// If onlyPublic is true the PrivateKey of the returned KeyPair is nil.
+func (cs *CommandSet) ExportKey(derive bool, makeCurrent bool, onlyPublic bool, path string) (*types.KeyPair, error) {
 	var p2 uint8
 	if onlyPublic {
 		p2 = P2ExportKeyPublicOnly
@@ -315,28 +792,55 @@ func (cs *CommandSet) ExportKey(derive bool, makeCurrent bool, onlyPublic bool,
 		p2 = P2ExportKeyPrivateAndPublic
 	}
 
+	return cs.exportKey(exportKeyP1(derive, makeCurrent), p2, path)
+}
+
+// ExportExtendedPublicKey exports the public key and chain code of the current key,
+// or of the key at path if derive is true.
+func (cs *CommandSet) ExportExtendedPublicKey(derive bool, makeCurrent bool, path string) (*types.KeyPair, error) {
+	return cs.exportKey(exportKeyP1(derive, makeCurrent), P2ExportKeyExtendedPublic, path)
+}
+
+func (cs *CommandSet) exportKey(p1 uint8, p2 uint8, path string) (*types.KeyPair, error) {
 	cmd, err := NewCommandExportKey(p1, p2, path)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 	err = cs.checkOK(resp, err)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return types.ParseExportKeyResponse(resp.Data)
+	return types.ParseExportedKeyPair(resp.Data)
 }
 
+// SetPinlessPath sets the absolute path of the key that can sign without PIN verification.
+// Only one pinless path can be set at a time and setting a new one replaces the previous one.
+// An empty path clears the current pinless path.
+// The applet doesn't report the pinless path, so the path set is kept and returned by PinlessPath.
 func (cs *CommandSet) SetPinlessPath(path string) error {
 	cmd, err := NewCommandSetPinlessPath(path)
 	if err != nil {
 		return err
 	}
 
-	resp, err := cs.sc.Send(cmd)
-	return cs.checkOK(resp, err)
+	resp, err := cs.sendSecure(cmd)
+	if err = cs.checkOK(resp, err); err != nil {
+		return err
+	}
+
+	cs.pinlessPath = path
+
+	return nil
+}
+
+// PinlessPath returns the pinless path set by SetPinlessPath, empty if none was set or
+// if it was cleared, by SetPinlessPath or along with the key by RemoveKey or FactoryReset.
+// A path set by another client or in a previous session is not known.
+func (cs *CommandSet) PinlessPath() string {
+	return cs.pinlessPath
 }
 
 func (cs *CommandSet) Sign(data []byte) (*types.Signature, error) {
@@ -345,7 +849,7 @@ func (cs *CommandSet) Sign(data []byte) (*types.Signature, error) {
 		return nil, err
 	}
 
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 	if err = cs.checkOK(resp, err); err != nil {
 		return nil, err
 	}
@@ -359,7 +863,45 @@ func (cs *CommandSet) SignWithPath(data []byte, path string) (*types.Signature,
 		return nil, err
 	}
 
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
+	if err = cs.checkOK(resp, err); err != nil {
+		return nil, err
+	}
+
+	return types.ParseSignature(data, resp.Data)
+}
+
+// SignMessage hashes msg with hasher and signs the hash with the current key.
+// If hasher is nil, msg is hashed with Keccak-256. For personal_sign style signatures,
+// pass a hasher adding the Ethereum message prefix, like accounts.TextHash.
+// It fails with ErrInvalidHashLength if the hash is not 32 bytes, without sending anything.
+func (cs *CommandSet) SignMessage(msg []byte, hasher func([]byte) []byte) (*types.Signature, error) {
+	if hasher == nil {
+		hasher = func(data []byte) []byte { return ethcrypto.Keccak256(data) }
+	}
+
+	hash := hasher(msg)
+	if len(hash) != 32 {
+		return nil, ErrInvalidHashLength
+	}
+
+	return cs.Sign(hash)
+}
+
+// DeriveAndSign derives the key at path and signs data with it in a single command.
+// If makeCurrent is true, the derived key also becomes the current key.
+func (cs *CommandSet) DeriveAndSign(data []byte, path string, makeCurrent bool) (*types.Signature, error) {
+	p1 := uint8(P1SignDerive)
+	if makeCurrent {
+		p1 = P1SignDeriveAndMakeCurrent
+	}
+
+	cmd, err := NewCommandSign(data, p1, path)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cs.sendSecure(cmd)
 	if err = cs.checkOK(resp, err); err != nil {
 		return nil, err
 	}
@@ -367,6 +909,24 @@ func (cs *CommandSet) SignWithPath(data []byte, path string) (*types.Signature,
 	return types.ParseSignature(data, resp.Data)
 }
 
+// SignTx signs txHash with the current key for a transaction on chainID.
+// txHash is the hash the go-ethereum EIP155Signer computes from the RLP encoded transaction,
+// the returned signature has its V replay protected as described in EIP-155.
+func (cs *CommandSet) SignTx(chainID *big.Int, txHash []byte) (*types.TxSignature, error) {
+	if chainID == nil || chainID.Sign() <= 0 {
+		return nil, ErrInvalidChainID
+	}
+
+	sig, err := cs.Sign(txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.NewTxSignature(sig, chainID), nil
+}
+
+// SignPinless signs data with the key at the pinless path, without secure channel or PIN
+// verification. It fails with ErrPinlessPathNotSet if no path was set with SetPinlessPath.
 func (cs *CommandSet) SignPinless(data []byte) (*types.Signature, error) {
 	cmd, err := NewCommandSign(data, P1SignPinless, "")
 	if err != nil {
@@ -374,6 +934,10 @@ func (cs *CommandSet) SignPinless(data []byte) (*types.Signature, error) {
 	}
 
 	resp, err := cs.c.Send(cmd)
+	if resp != nil && resp.Sw == SwReferencedDataNotFound {
+		return nil, ErrPinlessPathNotSet
+	}
+
 	if err = cs.checkOK(resp, err); err != nil {
 		return nil, err
 	}
@@ -381,16 +945,55 @@ func (cs *CommandSet) SignPinless(data []byte) (*types.Signature, error) {
 	return types.ParseSignature(data, resp.Data)
 }
 
+// LoadSeed loads a 64 bytes BIP39 seed as the card master key and returns the resulting key UID.
 func (cs *CommandSet) LoadSeed(seed []byte) ([]byte, error) {
+	if len(seed) != seedLength {
+		return nil, ErrInvalidSeedLength
+	}
+
 	cmd := NewCommandLoadSeed(seed)
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
 	if err = cs.checkOK(resp, err); err != nil {
 		return nil, err
 	}
 
+	cs.ApplicationInfo.KeyUID = resp.Data
+
 	return resp.Data, nil
 }
 
+// LoadSeedFromMnemonic loads the BIP39 seed of mnemonic and passphrase as the card master key
+// and returns the resulting key UID. It fails with ErrInvalidMnemonic, without sending anything,
+// if mnemonic has unknown words or a wrong checksum.
+func (cs *CommandSet) LoadSeedFromMnemonic(mnemonic, passphrase string) ([]byte, error) {
+	seed, err := seedFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs.LoadSeed(seed)
+}
+
+// LoadKey loads keyPair as the card master key and returns the resulting key UID.
+// Key pairs with a chain code are loaded as extended keys.
+//
+// A key pair returned by ExportKey can be loaded on another card, but the applet only exports
+// private keys of the EIP-1581 paths and never a private key with its chain code, so a card
+// can't be copied this way: back up the mnemonic or the seed loaded with LoadSeed instead.
+func (cs *CommandSet) LoadKey(keyPair *types.KeyPair) ([]byte, error) {
+	cmd := NewCommandLoadKey(keyPair)
+	resp, err := cs.sendSecureChained(cmd)
+	if err = cs.checkOK(resp, err); err != nil {
+		return nil, err
+	}
+
+	cs.ApplicationInfo.KeyUID = resp.Data
+
+	return resp.Data, nil
+}
+
+// GetData returns the data record of type typ.
+// The NDEF record can be read without opening the secure channel.
 func (cs *CommandSet) GetData(typ uint8) ([]byte, error) {
 	cmd := NewCommandGetData(typ)
 	resp, err := cs.sc.Send(cmd)
@@ -401,22 +1004,71 @@ func (cs *CommandSet) GetData(typ uint8) ([]byte, error) {
 	return resp.Data, nil
 }
 
-func (cs *CommandSet) StoreData(typ uint8, data []byte) error {
-	cmd := NewCommandStoreData(typ, data)
-	resp, err := cs.sc.Send(cmd)
-	return cs.checkOK(resp, err)
+// StoreData stores data in the record of type typ, replacing its previous content.
+// Data longer than MaxSecureDataLength is sent chaining multiple commands.
+// It returns the number of bytes acknowledged by the card, which is less than len(data)
+// if one of the chained commands failed and the record was only partially written.
+func (cs *CommandSet) StoreData(typ uint8, data []byte) (int, error) {
+	if len(data) > MaxStoreDataLength {
+		return 0, ErrDataTooLong
+	}
+
+	stored := 0
+	cmds := NewCommandStoreData(typ, data).Chain(secureDataLength(cs.maxCommandLength))
+	for _, cmd := range cmds {
+		// the secure channel replaces the data with its encrypted form
+		n := len(cmd.Data)
+		resp, err := cs.sendSecure(cmd)
+		if err = cs.checkOK(resp, err); err != nil {
+			return stored, err
+		}
+
+		stored += n
+	}
+
+	return stored, nil
 }
 
-func (cs *CommandSet) mutualAuthenticate() error {
+// MutuallyAuthenticate sends a random challenge through the newly opened secure channel
+// and returns the card response. Since the response MAC is verified, a successful call
+// proves that both sides derived the same session keys. OpenSecureChannel already calls it.
+func (cs *CommandSet) MutuallyAuthenticate() ([]byte, error) {
 	data := make([]byte, 32)
-	if _, err := rand.Read(data); err != nil {
-		return err
+	if _, err := io.ReadFull(cs.random, data); err != nil {
+		return nil, err
 	}
 
 	cmd := NewCommandMutuallyAuthenticate(data)
-	resp, err := cs.sc.Send(cmd)
+	resp, err := cs.sendSecure(cmd)
+	if err = cs.checkOK(resp, err); err != nil {
+		return nil, err
+	}
 
-	return cs.checkOK(resp, err)
+	return resp.Data, nil
+}
+
+// sendSecure sends cmd through the secure channel.
+// It fails with ErrSecureChannelNotOpen if the card supports a secure channel that hasn't been opened.
+func (cs *CommandSet) sendSecure(cmd *apdu.Command) (*apdu.Response, error) {
+	if cs.ApplicationInfo.HasSecureChannelCapability() && !cs.sc.IsOpen() {
+		return nil, ErrSecureChannelNotOpen
+	}
+
+	return cs.sc.Send(cmd)
+}
+
+// sendSecureChained sends cmd through the secure channel, chaining multiple commands
+// if its data doesn't fit in one. It returns the response to the last command.
+func (cs *CommandSet) sendSecureChained(cmd *apdu.Command) (*apdu.Response, error) {
+	cmds := cmd.Chain(secureDataLength(cs.maxCommandLength))
+	for _, c := range cmds[:len(cmds)-1] {
+		resp, err := cs.sendSecure(c)
+		if err = cs.checkOK(resp, err); err != nil {
+			return nil, err
+		}
+	}
+
+	return cs.sendSecure(cmds[len(cmds)-1])
 }
 
 func (cs *CommandSet) checkOK(resp *apdu.Response, err error, allowedResponses ...uint16) error {
@@ -434,5 +1086,47 @@ func (cs *CommandSet) checkOK(resp *apdu.Response, err error, allowedResponses .
 		}
 	}
 
+	if resp.Sw == SwConditionsNotSatisfied {
+		return ErrConditionsNotSatisfied
+	}
+
 	return apdu.NewErrBadResponse(resp.Sw, "unexpected response")
 }
+
+// wrongCredentialsAttempts returns the remaining attempts encoded in a 0x63CX response.
+func wrongCredentialsAttempts(resp *apdu.Response) (int, bool) {
+	if resp == nil || resp.Sw&0xFFF0 != SwWrongCredentials {
+		return 0, false
+	}
+
+	return int(resp.Sw & 0x000F), true
+}
+
+// secureDataLength returns the longest plain text whose padded ciphertext and MAC fit in length bytes.
+func secureDataLength(length int) int {
+	return (length-16)/16*16 - 1
+}
+
+func exportKeyP1(derive bool, makeCurrent bool) uint8 {
+	if !derive {
+		return P1ExportKeyCurrent
+	} else if !makeCurrent {
+		return P1ExportKeyDerive
+	}
+
+	return P1ExportKeyDeriveAndMakeCurrent
+}
+
+func isDigits(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,1018 @@
+package keycard
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/crypto"
+	"github.com/status-im/keycard-go/globalplatform"
+	"github.com/status-im/keycard-go/hexutils"
+	keycardio "github.com/status-im/keycard-go/io"
+	"github.com/status-im/keycard-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockChannel(sws ...uint16) *keycardio.MockChannel {
+	c := keycardio.NewMockChannel()
+	for _, sw := range sws {
+		c.Respond(nil, sw)
+	}
+
+	return c
+}
+
+// applicationInfoResponse returns the SELECT response of an initialized card without keys.
+// A random secure channel key is used if pubKey is nil.
+func applicationInfoResponse(pubKey []byte) []byte {
+	if pubKey == nil {
+		key, _ := ethcrypto.GenerateKey()
+		pubKey = ethcrypto.FromECDSAPub(&key.PublicKey)
+	}
+
+	tpl := append([]byte{0x8F, 0x10}, bytes.Repeat([]byte{0x01}, 16)...)
+	tpl = append(tpl, 0x80, 0x41)
+	tpl = append(tpl, pubKey...)
+	tpl = append(tpl, 0x02, 0x02, 0x03, 0x01, 0x02, 0x01, 0x05, 0x8E, 0x00)
+
+	return append([]byte{types.TagApplicationInfoTemplate, 0x81, byte(len(tpl))}, tpl...)
+}
+
+// signResponse returns a SIGN response with the signature of hash by a new key.
+func signResponse(t testing.TB, hash []byte) ([]byte, []byte) {
+	key, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	sig, err := ethcrypto.Sign(hash, key)
+	require.NoError(t, err)
+
+	tpl := append([]byte{0x80, 0x41}, ethcrypto.FromECDSAPub(&key.PublicKey)...)
+	tpl = append(tpl, 0x30, 0x44, 0x02, 0x20)
+	tpl = append(tpl, sig[:32]...)
+	tpl = append(tpl, 0x02, 0x20)
+	tpl = append(tpl, sig[32:64]...)
+
+	return append([]byte{types.TagSignatureTemplate, 0x81, byte(len(tpl))}, tpl...), sig
+}
+
+func TestCommandSet_VerifyPIN(t *testing.T) {
+	c := newMockChannel(apdu.SwOK, 0x63C2, 0x63C0, 0x6985)
+	cs := NewCommandSet(c)
+
+	err := cs.VerifyPIN("123456")
+	require.NoError(t, err)
+	assert.Equal(t, uint8(InsVerifyPIN), c.Sent[0].Ins)
+	assert.Equal(t, []byte("123456"), c.Sent[0].Data)
+
+	err = cs.VerifyPIN("000000")
+	assert.Equal(t, &WrongPINError{RemainingAttempts: 2}, err)
+
+	err = cs.VerifyPIN("000000")
+	assert.Equal(t, ErrPINBlocked, err)
+
+	err = cs.VerifyPIN("000000")
+	assert.Equal(t, ErrConditionsNotSatisfied, err)
+}
+
+func TestCommandSet_ChangePIN(t *testing.T) {
+	c := newMockChannel(apdu.SwOK, apdu.SwOK, SwConditionsNotSatisfied)
+	cs := NewCommandSet(c)
+
+	assert.Equal(t, ErrInvalidPIN, cs.ChangePIN("12345"))
+	assert.Equal(t, ErrInvalidPIN, cs.ChangePIN("12345a"))
+	assert.Equal(t, ErrInvalidPUK, cs.ChangePUK("12345678901"))
+	assert.Empty(t, c.Sent)
+
+	require.NoError(t, cs.ChangePIN("123456"))
+	assert.Equal(t, uint8(P1ChangePinPIN), c.Sent[0].P1)
+
+	require.NoError(t, cs.ChangePUK("123456789012"))
+	assert.Equal(t, uint8(P1ChangePinPUK), c.Sent[1].P1)
+
+	assert.Equal(t, ErrConditionsNotSatisfied, cs.ChangePairingSecret("pairing"))
+	assert.Equal(t, uint8(P1ChangePinPairingSecret), c.Sent[2].P1)
+	assert.Len(t, c.Sent[2].Data, 32)
+}
+
+func TestCommandSet_ChangePINChecked(t *testing.T) {
+	c := newMockChannel(apdu.SwOK, apdu.SwOK, 0x63C1)
+	cs := NewCommandSet(c)
+
+	assert.Equal(t, ErrInvalidPIN, cs.ChangePINChecked("123456", "1234"))
+	assert.Empty(t, c.Sent)
+
+	require.NoError(t, cs.ChangePINChecked("123456", "654321"))
+	require.Len(t, c.Sent, 2)
+	assert.Equal(t, uint8(InsVerifyPIN), c.Sent[0].Ins)
+	assert.Equal(t, []byte("123456"), c.Sent[0].Data)
+	assert.Equal(t, uint8(InsChangePIN), c.Sent[1].Ins)
+	assert.Equal(t, []byte("654321"), c.Sent[1].Data)
+
+	err := cs.ChangePINChecked("000000", "654321")
+	assert.Equal(t, &WrongPINError{RemainingAttempts: 1}, err)
+	assert.Len(t, c.Sent, 3)
+}
+
+func TestCommandSet_UnblockPIN(t *testing.T) {
+	c := newMockChannel(apdu.SwOK, 0x63C4, 0x63C0)
+	cs := NewCommandSet(c)
+
+	assert.Equal(t, ErrInvalidPUK, cs.UnblockPIN("1234", "123456"))
+	assert.Equal(t, ErrInvalidPIN, cs.UnblockPIN("123456789012", "1234"))
+	assert.Empty(t, c.Sent)
+
+	require.NoError(t, cs.UnblockPIN("123456789012", "123456"))
+	assert.Equal(t, []byte("123456789012123456"), c.Sent[0].Data)
+
+	err := cs.UnblockPIN("000000000000", "123456")
+	assert.Equal(t, &WrongPUKError{RemainingAttempts: 4}, err)
+
+	err = cs.UnblockPIN("000000000000", "123456")
+	assert.Equal(t, ErrPUKBlocked, err)
+}
+
+func TestCommandSet_GenerateKey(t *testing.T) {
+	keyUID := bytes.Repeat([]byte{0xAA}, 32)
+	noKey := hexutils.HexToBytes("A3 09 02 01 03 02 01 05 01 01 00")
+	withKey := hexutils.HexToBytes("A3 09 02 01 03 02 01 05 01 01 FF")
+	c := keycardio.NewMockChannel().
+		Expect(InsGetStatus, noKey, apdu.SwOK).
+		Expect(InsGenerateKey, keyUID, apdu.SwOK).
+		Expect(InsGetStatus, withKey, apdu.SwOK).
+		Expect(InsGenerateKey, keyUID, apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	uid, err := cs.GenerateKey(false)
+	require.NoError(t, err)
+	assert.Equal(t, keyUID, uid)
+	assert.Equal(t, keyUID, cs.ApplicationInfo.KeyUID)
+
+	_, err = cs.GenerateKey(false)
+	assert.Equal(t, ErrKeyAlreadyExists, err)
+	assert.Len(t, c.Sent, 3)
+
+	_, err = cs.GenerateKey(true)
+	require.NoError(t, err)
+	assert.Len(t, c.Sent, 4)
+}
+
+func TestCommandSet_GenerateKeyStaleApplicationInfo(t *testing.T) {
+	// a key was loaded since the last Select, which reported none
+	c := keycardio.NewMockChannel().
+		Expect(InsGetStatus, hexutils.HexToBytes("A3 09 02 01 03 02 01 05 01 01 FF"), apdu.SwOK)
+	cs := NewCommandSet(c)
+	cs.ApplicationInfo.KeyUID = nil
+
+	_, err := cs.GenerateKey(false)
+	assert.Equal(t, ErrKeyAlreadyExists, err)
+	assert.Len(t, c.Sent, 1)
+}
+
+func TestCommandSet_GenerateMnemonic(t *testing.T) {
+	c := keycardio.NewMockChannel().
+		Respond([]byte{0x00, 0x01, 0x07, 0xFF, 0x04, 0x00}, apdu.SwOK).
+		Respond([]byte{0x00, 0x01, 0x07}, apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	_, err := cs.GenerateMnemonic(3)
+	assert.Equal(t, ErrBadChecksumSize, err)
+	_, err = cs.GenerateMnemonic(9)
+	assert.Equal(t, ErrBadChecksumSize, err)
+
+	indexes, err := cs.GenerateMnemonic(4)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(4), c.Sent[0].P1)
+	assert.Equal(t, []int{1, 2047, 1024}, indexes)
+
+	_, err = cs.GenerateMnemonic(4)
+	assert.Equal(t, ErrBadMnemonicResponse, err)
+}
+
+func TestCommandSet_LoadKey(t *testing.T) {
+	keyUID := bytes.Repeat([]byte{0xBB}, 32)
+	c := keycardio.NewMockChannel().
+		Respond(keyUID, apdu.SwOK).
+		Respond(nil, apdu.SwOK).
+		Respond(nil, apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	kp := &types.KeyPair{PrivateKey: bytes.Repeat([]byte{0x01}, 32)}
+	uid, err := cs.LoadKey(kp)
+	require.NoError(t, err)
+	assert.Equal(t, keyUID, uid)
+	assert.Equal(t, uint8(P1LoadKeyECC), c.Sent[0].P1)
+	assert.Equal(t, kp.Serialize(), c.Sent[0].Data)
+
+	kp.ChainCode = bytes.Repeat([]byte{0x02}, 32)
+	_, err = cs.LoadKey(kp)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(P1LoadKeyExtendedECC), c.Sent[1].P1)
+
+	_, err = cs.LoadSeed(make([]byte, 32))
+	assert.Equal(t, ErrInvalidSeedLength, err)
+
+	_, err = cs.LoadSeed(make([]byte, 64))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(P1LoadKeySeed), c.Sent[2].P1)
+}
+
+func TestCommandSet_LoadSeedFromMnemonic(t *testing.T) {
+	keyUID := bytes.Repeat([]byte{0x01}, 32)
+	c := keycardio.NewMockChannel().Expect(InsLoadKey, keyUID, apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	_, err := cs.LoadSeedFromMnemonic("not a mnemonic", "")
+	assert.True(t, errors.Is(err, ErrInvalidMnemonic))
+	assert.Empty(t, c.Sent)
+
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	uid, err := cs.LoadSeedFromMnemonic(mnemonic, "TREZOR")
+	require.NoError(t, err)
+	assert.Equal(t, keyUID, uid)
+
+	seed := hexutils.HexToBytes("C55257C360C07C72029AEBC1B53C05ED0362ADA38EAD3E3E9EFA3708E53495531F09A6987599D18264C1E1C92F2CF141630C7A3C4AB7C81B2F001698E7463B04")
+	assert.Equal(t, NewCommandLoadSeed(seed), c.Sent[0])
+}
+
+func TestCommandSet_StoreData(t *testing.T) {
+	c := newMockChannel(apdu.SwOK, apdu.SwOK, apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	_, err := cs.StoreData(P1StoreDataPublic, make([]byte, MaxStoreDataLength+1))
+	assert.Equal(t, ErrDataTooLong, err)
+	assert.Empty(t, c.Sent)
+
+	data := bytes.Repeat([]byte{0x01}, MaxSecureDataLength)
+	n, err := cs.StoreData(P1StoreDataNDEF, data)
+	require.NoError(t, err)
+	assert.Equal(t, len(data), n)
+	assert.Equal(t, uint8(InsStoreData), c.Sent[0].Ins)
+	assert.Equal(t, uint8(P1StoreDataNDEF), c.Sent[0].P1)
+	assert.Equal(t, data, c.Sent[0].Data)
+
+	data = bytes.Repeat([]byte{0x02}, MaxSecureDataLength+1)
+	n, err = cs.StoreData(P1StoreDataPublic, data)
+	require.NoError(t, err)
+	assert.Equal(t, len(data), n)
+	require.Len(t, c.Sent, 3)
+	assert.Equal(t, uint8(globalplatform.ClaGp|apdu.ClaChaining), c.Sent[1].Cla)
+	assert.Len(t, c.Sent[1].Data, MaxSecureDataLength)
+	assert.Equal(t, uint8(globalplatform.ClaGp), c.Sent[2].Cla)
+	assert.Len(t, c.Sent[2].Data, 1)
+}
+
+// dataRecordChannel stores the data of STORE DATA commands and returns it to GET DATA.
+type dataRecordChannel struct {
+	records map[uint8][]byte
+	pending []byte
+	failAt  int
+	sent    int
+}
+
+func (c *dataRecordChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	c.sent++
+	if c.sent == c.failAt {
+		return apdu.ParseResponse([]byte{0x6A, 0x80})
+	}
+
+	switch cmd.Ins {
+	case InsStoreData:
+		c.pending = append(c.pending, cmd.Data...)
+		if cmd.Cla&apdu.ClaChaining == 0 {
+			c.records[cmd.P1] = c.pending
+			c.pending = nil
+		}
+
+		return apdu.ParseResponse([]byte{0x90, 0x00})
+	case InsGetData:
+		return apdu.ParseResponse(append(append([]byte{}, c.records[cmd.P1]...), 0x90, 0x00))
+	}
+
+	return apdu.ParseResponse([]byte{0x6D, 0x00})
+}
+
+func TestCommandSet_StoreDataRoundTrip(t *testing.T) {
+	c := &dataRecordChannel{records: map[uint8][]byte{}}
+	cs := NewCommandSet(c)
+
+	blob := make([]byte, 100)
+	_, err := rand.Read(blob)
+	require.NoError(t, err)
+
+	n, err := cs.StoreData(P1StoreDataPublic, blob)
+	require.NoError(t, err)
+	assert.Equal(t, 100, n)
+
+	data, err := cs.GetData(P1StoreDataPublic)
+	require.NoError(t, err)
+	assert.Equal(t, blob, data)
+
+	// the second chained command fails, only the first one was acknowledged
+	c.sent, c.failAt = 0, 2
+	require.NoError(t, cs.SetMaxCommandLength(64))
+	n, err = cs.StoreData(P1StoreDataPublic, blob)
+	assert.Error(t, err)
+	assert.Equal(t, secureDataLength(64), n)
+}
+
+func TestCommandSet_UnpairOthers(t *testing.T) {
+	c := newMockChannel(apdu.SwOK, apdu.SwOK, apdu.SwOK, apdu.SwOK)
+	cs := NewCommandSet(c)
+	cs.ApplicationInfo.AvailableSlots = []byte{0}
+
+	assert.Equal(t, ErrPairingInfoNotSet, cs.UnpairOthers())
+
+	cs.SetPairingInfo([]byte{0x01}, 2)
+	require.NoError(t, cs.UnpairOthers())
+
+	indexes := []uint8{}
+	for _, cmd := range c.Sent {
+		assert.Equal(t, uint8(InsUnpair), cmd.Ins)
+		indexes = append(indexes, cmd.P1)
+	}
+
+	assert.Equal(t, []uint8{0, 1, 3, 4}, indexes)
+	assert.Equal(t, MaxPairingSlots-1, cs.PairingSlots().Free)
+}
+
+func TestCommandSet_SecureChannelNotOpen(t *testing.T) {
+	c := newMockChannel(apdu.SwOK)
+	cs := NewCommandSet(c)
+	cs.ApplicationInfo.Capabilities = types.CapabilityAll
+
+	_, err := cs.Sign(make([]byte, 32))
+	assert.Equal(t, ErrSecureChannelNotOpen, err)
+	assert.Equal(t, ErrSecureChannelNotOpen, cs.VerifyPIN("123456"))
+	assert.Empty(t, c.Sent)
+
+	// the NDEF record is public
+	_, err = cs.GetData(P1StoreDataNDEF)
+	require.NoError(t, err)
+	assert.Len(t, c.Sent, 1)
+}
+
+func TestCommandSet_FactoryReset(t *testing.T) {
+	c := newMockChannel()
+	cs := NewCommandSet(c)
+	cs.ApplicationInfo.Capabilities = types.CapabilityAll
+
+	assert.Equal(t, ErrFactoryResetNotSupported, cs.FactoryReset())
+	assert.Empty(t, c.Sent)
+
+	cs.ApplicationInfo.Capabilities = types.CapabilityAll | types.CapabilityFactoryReset
+	cs.SetPairingInfo([]byte{0x01}, 0)
+	c.Expect(InsFactoryReset, nil, apdu.SwOK)
+	c.Expect(globalplatform.InsSelect, []byte{types.TagSelectResponsePreInitialized, 0x00}, apdu.SwOK)
+
+	require.NoError(t, cs.FactoryReset())
+	assert.Equal(t, uint8(P1FactoryResetMagic), c.Sent[0].P1)
+	assert.Equal(t, uint8(P2FactoryResetMagic), c.Sent[0].P2)
+	assert.Nil(t, cs.PairingInfo)
+	assert.False(t, cs.ApplicationInfo.Initialized)
+}
+
+func TestCommandSet_RetryCounts(t *testing.T) {
+	status := hexutils.HexToBytes("A3 09 02 01 02 02 01 04 01 01 FF")
+	c := keycardio.NewMockChannel().
+		Expect(InsGetStatus, status, apdu.SwOK).
+		Expect(InsGetStatus, status, apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	pinRetries, err := cs.PINRetryCount()
+	require.NoError(t, err)
+	assert.Equal(t, 2, pinRetries)
+
+	pukRetries, err := cs.PUKRetryCount()
+	require.NoError(t, err)
+	assert.Equal(t, 4, pukRetries)
+
+	for _, cmd := range c.Sent {
+		assert.Equal(t, uint8(P1GetStatusApplication), cmd.P1)
+	}
+}
+
+func TestCommandSet_PairNoAvailableSlots(t *testing.T) {
+	c := newMockChannel(SwNoAvailablePairingSlots)
+	cs := NewCommandSet(c)
+	cs.ApplicationInfo.AvailableSlots = []byte{0x00}
+
+	assert.Equal(t, ErrNoAvailablePairingSlots, cs.Pair("KeycardTest"))
+	assert.Empty(t, c.Sent)
+
+	cs.ApplicationInfo.AvailableSlots = []byte{0x01}
+	assert.Equal(t, ErrNoAvailablePairingSlots, cs.Pair("KeycardTest"))
+	assert.Len(t, c.Sent, 1)
+}
+
+func TestCommandSet_PairAndOpen(t *testing.T) {
+	c := newMockChannel(SwNoAvailablePairingSlots)
+	cs := NewCommandSet(c)
+
+	err := cs.PairAndOpen("KeycardTest", "123456")
+	assert.ErrorIs(t, err, ErrNoAvailablePairingSlots)
+	assert.Contains(t, err.Error(), "pair: ")
+	assert.Nil(t, cs.PairingInfo)
+	assert.Len(t, c.Sent, 1)
+
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	card := &fakeCard{key: cardKey, pairingPass: "KeycardTest"}
+	cs = NewCommandSet(card)
+	require.NoError(t, cs.Select())
+
+	require.NoError(t, cs.PairAndOpen("KeycardTest", "123456"))
+	assert.Equal(t, &types.PairingInfo{Key: card.pairingKey, Index: 1}, cs.PairingInfo)
+	assert.True(t, cs.IsSecureChannelOpen())
+}
+
+func TestCommandSet_Identify(t *testing.T) {
+	c := newMockChannel(apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	_, err := cs.Identify([]byte{0x01})
+	assert.Equal(t, ErrInvalidChallengeLength, err)
+	assert.Empty(t, c.Sent)
+
+	_, err = cs.Identify(nil)
+	assert.Error(t, err)
+	require.Len(t, c.Sent, 1)
+	assert.Equal(t, uint8(InsIdentify), c.Sent[0].Ins)
+	assert.Len(t, c.Sent[0].Data, 32)
+}
+
+func TestCommandSet_DeriveAndSign(t *testing.T) {
+	c := newMockChannel(apdu.SwOK, apdu.SwOK)
+	cs := NewCommandSet(c)
+	hash := bytes.Repeat([]byte{0x01}, 32)
+
+	// the empty responses can't be parsed, only the commands are checked
+	_, err := cs.DeriveAndSign(hash, "m/44'/60'/0'/0/0", false)
+	assert.Error(t, err)
+	_, err = cs.DeriveAndSign(hash, "../0", true)
+	assert.Error(t, err)
+
+	require.Len(t, c.Sent, 2)
+	assert.Equal(t, uint8(InsSign), c.Sent[0].Ins)
+	assert.Equal(t, uint8(P1SignDerive), c.Sent[0].P1)
+	assert.Len(t, c.Sent[0].Data, 32+5*4)
+	assert.Equal(t, uint8(P1SignDeriveAndMakeCurrent|P1DeriveKeyFromParent), c.Sent[1].P1)
+}
+
+func TestCommandSet_SignTx(t *testing.T) {
+	c := newMockChannel()
+	cs := NewCommandSet(c)
+
+	_, err := cs.SignTx(nil, make([]byte, 32))
+	assert.Equal(t, ErrInvalidChainID, err)
+	_, err = cs.SignTx(big.NewInt(0), make([]byte, 32))
+	assert.Equal(t, ErrInvalidChainID, err)
+	assert.Empty(t, c.Sent)
+
+	txHash := bytes.Repeat([]byte{0x01}, 32)
+	resp, sig := signResponse(t, txHash)
+	c.Respond(resp, apdu.SwOK)
+
+	txSig, err := cs.SignTx(big.NewInt(5), txHash)
+	require.NoError(t, err)
+	assert.Equal(t, sig[64], txSig.RecoveryID())
+	assert.Equal(t, big.NewInt(45+int64(sig[64])), txSig.V())
+}
+
+func TestCommandSet_SignMessage(t *testing.T) {
+	msg := []byte("keycard")
+	hash := ethcrypto.Keccak256(msg)
+	resp, sig := signResponse(t, hash)
+	c := keycardio.NewMockChannel().Respond(resp, apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	s, err := cs.SignMessage(msg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, sig[:32], s.R())
+	assert.Equal(t, hash, c.Sent[0].Data)
+
+	_, err = cs.SignMessage(msg, func(data []byte) []byte { return data })
+	assert.Equal(t, ErrInvalidHashLength, err)
+	assert.Len(t, c.Sent, 1)
+}
+
+func TestCommandSet_PreviewCommand(t *testing.T) {
+	c := keycardio.NewCaptureChannel()
+	cs := NewCommandSet(c)
+	hash := bytes.Repeat([]byte{0x01}, 32)
+
+	_, err := cs.SignWithPath(hash, "m/44'/60'/0'/0/0")
+	assert.Equal(t, keycardio.ErrCommandCaptured, err)
+
+	expected, err := NewCommandSign(hash, P1SignDerive, "m/44'/60'/0'/0/0")
+	require.NoError(t, err)
+	assert.Equal(t, expected, c.Last())
+}
+
+func TestCommandSet_Init(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	preInit := append([]byte{types.TagSelectResponsePreInitialized, 0x41}, ethcrypto.FromECDSAPub(&cardKey.PublicKey)...)
+	secrets, err := NewSecrets("123456", "123456789012", "KeycardTest")
+	require.NoError(t, err)
+
+	// the secure channel key is only known after a Select
+	c := newMockChannel()
+	cs := NewCommandSet(c)
+	_, err = cs.Init(secrets, true)
+	assert.Equal(t, ErrSecureChannelSecretNotSet, err)
+	assert.Empty(t, c.Sent)
+
+	// the card was initialized since the last Select
+	c.Expect(globalplatform.InsSelect, applicationInfoResponse(nil), apdu.SwOK)
+	_, err = cs.Init(secrets, false)
+	assert.Equal(t, ErrAlreadyInitialized, err)
+	assert.Len(t, c.Sent, 1)
+
+	c.Expect(globalplatform.InsSelect, preInit, apdu.SwOK)
+	_, err = cs.Init(&Secrets{pin: "123456", puk: "1234", pairingPass: "pass"}, false)
+	assert.Equal(t, ErrInvalidPUK, err)
+	assert.Len(t, c.Sent, 2)
+
+	// forced, the card rejects INIT itself
+	cs.ApplicationInfo.Initialized = true
+	c.Expect(InsInit, nil, SwInsNotSupported)
+	_, err = cs.Init(secrets, true)
+	assert.Equal(t, ErrAlreadyInitialized, err)
+
+	c.Expect(globalplatform.InsSelect, preInit, apdu.SwOK)
+	c.Expect(InsInit, nil, apdu.SwOK)
+	c.Expect(globalplatform.InsSelect, applicationInfoResponse(nil), apdu.SwOK)
+	instanceUID, err := cs.Init(secrets, false)
+	require.NoError(t, err)
+	assert.True(t, cs.ApplicationInfo.Initialized)
+	assert.Equal(t, bytes.Repeat([]byte{0x01}, 16), instanceUID)
+	assert.Equal(t, cs.ApplicationInfo.InstanceUID, instanceUID)
+	assert.Zero(t, c.Pending())
+}
+
+func TestCommandSet_PairWrongPassword(t *testing.T) {
+	c := keycardio.NewMockChannel().
+		Expect(InsPair, make([]byte, 64), apdu.SwOK).
+		Expect(InsPair, make([]byte, 10), apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	assert.Equal(t, crypto.ErrWrongPairingPassword, cs.Pair("KeycardTest"))
+	assert.Equal(t, crypto.ErrInvalidCardCryptogram, cs.Pair("KeycardTest"))
+	assert.Len(t, c.Sent, 2)
+	assert.Nil(t, cs.PairingInfo)
+}
+
+func TestCommandSet_PairFixedRandom(t *testing.T) {
+	challenge := bytes.Repeat([]byte{0x01}, 32)
+	cardChallenge := bytes.Repeat([]byte{0x02}, 32)
+	salt := bytes.Repeat([]byte{0x03}, 32)
+	token := generatePairingToken("KeycardTest")
+
+	cryptogram := sha256.Sum256(append(append([]byte{}, token...), challenge...))
+	clientCryptogram := sha256.Sum256(append(append([]byte{}, token...), cardChallenge...))
+	pairingKey := sha256.Sum256(append(append([]byte{}, token...), salt...))
+
+	c := keycardio.NewMockChannel().
+		Expect(InsPair, append(cryptogram[:], cardChallenge...), apdu.SwOK).
+		Expect(InsPair, append([]byte{0x02}, salt...), apdu.SwOK)
+	cs := NewCommandSet(c)
+	cs.SetRandom(bytes.NewReader(challenge))
+
+	require.NoError(t, cs.Pair("KeycardTest"))
+	assert.Equal(t, challenge, c.Sent[0].Data)
+	assert.Equal(t, clientCryptogram[:], c.Sent[1].Data)
+	assert.Equal(t, &types.PairingInfo{Key: pairingKey[:], Index: 2}, cs.PairingInfo)
+
+	// the fixed source is exhausted
+	assert.Error(t, cs.Pair("KeycardTest"))
+	assert.Len(t, c.Sent, 2)
+}
+
+func TestCommandSet_ReplaySecureChannel(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	card := &fakeCard{key: cardKey, pairingKey: bytes.Repeat([]byte{0x01}, 32)}
+	card.response = hexutils.HexToBytes("A3 09 02 01 03 02 01 05 01 01 00 90 00")
+	random := bytes.Repeat([]byte{0x01}, 64)
+
+	session := func(c types.Channel) (*types.ApplicationStatus, error) {
+		cs := NewCommandSet(c)
+		cs.SetRandom(bytes.NewReader(random))
+		if err := cs.Select(); err != nil {
+			return nil, err
+		}
+
+		cs.SetPairingInfo(card.pairingKey, 0)
+		if err := cs.OpenSecureChannel(); err != nil {
+			return nil, err
+		}
+
+		return cs.GetStatusApplication()
+	}
+
+	rec := keycardio.NewRecordingChannel(card)
+	recorded, err := session(rec)
+	require.NoError(t, err)
+
+	replay := keycardio.NewReplayChannel(rec.Exchanges)
+	status, err := session(replay)
+	require.NoError(t, err)
+	assert.Equal(t, recorded, status)
+	assert.Zero(t, replay.Pending())
+}
+
+func TestCommandSet_SetPairingTokenIterations(t *testing.T) {
+	c := newMockChannel(apdu.SwOK, apdu.SwOK)
+	cs := NewCommandSet(c)
+	assert.Equal(t, ErrInvalidPairingTokenIterations, cs.SetPairingTokenIterations(0))
+
+	require.NoError(t, cs.ChangePairingSecret("KeycardTest"))
+	require.NoError(t, cs.SetPairingTokenIterations(1000))
+	require.NoError(t, cs.ChangePairingSecret("KeycardTest"))
+
+	assert.Equal(t, crypto.GeneratePairingToken("KeycardTest", crypto.PairingTokenIterations), c.Sent[0].Data)
+	assert.Equal(t, crypto.GeneratePairingToken("KeycardTest", 1000), c.Sent[1].Data)
+}
+
+func TestCommandSet_InitPairTokenIterations(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	card := &fakeCard{key: cardKey}
+	cs := NewCommandSet(card)
+	require.NoError(t, cs.SetPairingTokenIterations(1000))
+	require.NoError(t, cs.Select())
+
+	secrets, err := NewSecrets("123456", "123456789012", "KeycardTest")
+	require.NoError(t, err)
+	// the fake card always reports being initialized
+	_, err = cs.Init(secrets, true)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.GeneratePairingToken("KeycardTest", 1000), card.pairingToken)
+
+	require.NoError(t, cs.Pair("KeycardTest"))
+	assert.Equal(t, card.pairingKey, cs.PairingInfo.Key)
+
+	// a client using the default count can't pair
+	assert.Equal(t, crypto.ErrWrongPairingPassword, NewCommandSet(card).Pair("KeycardTest"))
+}
+
+func TestCommandSet_MutuallyAuthenticate(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	card := &fakeCard{key: cardKey, pairingKey: bytes.Repeat([]byte{0x01}, 32)}
+	cs := NewCommandSet(card)
+	require.NoError(t, cs.Select())
+	assert.False(t, cs.IsSecureChannelOpen())
+	cs.SetPairingInfo(card.pairingKey, 0)
+	require.NoError(t, cs.OpenSecureChannel())
+	assert.True(t, cs.IsSecureChannelOpen())
+
+	cardChallenge := bytes.Repeat([]byte{0xCC}, 32)
+	card.response = append(cardChallenge, 0x90, 0x00)
+	data, err := cs.MutuallyAuthenticate()
+	require.NoError(t, err)
+	assert.Equal(t, cardChallenge, data)
+
+	// the response MAC doesn't match the session keys
+	card.tamper = func(data []byte) { data[0] ^= 0x01 }
+	_, err = cs.MutuallyAuthenticate()
+	assert.Equal(t, ErrInvalidResponseMAC, err)
+	assert.False(t, cs.IsSecureChannelOpen())
+	card.tamper = nil
+
+	// the card derived different session keys and can't verify the command MAC
+	require.NoError(t, cs.OpenSecureChannel())
+	card.macKey = bytes.Repeat([]byte{0x02}, 32)
+	_, err = cs.MutuallyAuthenticate()
+	assert.True(t, IsAuthError(err))
+	assert.False(t, cs.IsSecureChannelOpen())
+
+	// wrong pairing key, the channel is not left open after the failed mutual authentication
+	cs.SetPairingInfo(bytes.Repeat([]byte{0x02}, 32), 0)
+	assert.Equal(t, ErrPairingInvalid, cs.OpenSecureChannel())
+	assert.False(t, cs.IsSecureChannelOpen())
+}
+
+func TestCommandSet_ReopenOrRepair(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	card := &fakeCard{key: cardKey, pairingPass: "KeycardTest"}
+	cs := NewCommandSet(card)
+	require.NoError(t, cs.Select())
+
+	cs.SetPairingInfo(bytes.Repeat([]byte{0x01}, 32), 0)
+	assert.Equal(t, ErrPairingInvalid, cs.OpenSecureChannel())
+
+	require.NoError(t, cs.ReopenOrRepair("KeycardTest"))
+	assert.True(t, cs.IsSecureChannelOpen())
+	assert.Equal(t, &types.PairingInfo{Key: card.pairingKey, Index: 1}, cs.PairingInfo)
+
+	// a valid pairing is reused
+	card.pairingPass = ""
+	require.NoError(t, cs.ReopenOrRepair("KeycardTest"))
+	assert.True(t, cs.IsSecureChannelOpen())
+
+	// the slot was paired again by another client: the card rejects the mutual authentication
+	cs.SetPairingInfo(bytes.Repeat([]byte{0x02}, 32), 1)
+	assert.Equal(t, ErrPairingInvalid, cs.OpenSecureChannel())
+
+	card.pairingPass = "KeycardTest"
+	require.NoError(t, cs.ReopenOrRepair("KeycardTest"))
+	assert.True(t, cs.IsSecureChannelOpen())
+	assert.Equal(t, card.pairingKey, cs.PairingInfo.Key)
+}
+
+func TestCommandSet_CurrentKeyPath(t *testing.T) {
+	c := keycardio.NewMockChannel().
+		Expect(InsGetStatus, hexutils.HexToBytes("8000002C8000003C800000000000000000000000"), apdu.SwOK).
+		Expect(InsGetStatus, nil, apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	path, err := cs.CurrentKeyPath()
+	require.NoError(t, err)
+	assert.Equal(t, "m/44'/60'/0'/0/0", path)
+	assert.Equal(t, uint8(P1GetStatusKeyPath), c.Sent[0].P1)
+
+	path, err = cs.CurrentKeyPath()
+	require.NoError(t, err)
+	assert.Equal(t, "m", path)
+}
+
+func TestCommandSet_PairingSlots(t *testing.T) {
+	c := newMockChannel()
+	cs := NewCommandSet(c)
+	assert.Equal(t, types.PairingSlots{Total: MaxPairingSlots, Free: -1, Current: -1}, cs.PairingSlots())
+
+	cs.ApplicationInfo.AvailableSlots = []byte{0x03}
+	cs.SetPairingInfo([]byte{0x01}, 1)
+	assert.Equal(t, types.PairingSlots{Total: MaxPairingSlots, Free: 3, Current: 1}, cs.PairingSlots())
+
+	cs.SetPairingInfo([]byte{0x01}, MaxPairingSlots)
+	assert.Equal(t, ErrInvalidPairingIndex, cs.OpenSecureChannel())
+	assert.Empty(t, c.Sent)
+}
+
+func TestCommandSet_ExportAndLoadKey(t *testing.T) {
+	exported := &types.KeyPair{
+		PrivateKey: bytes.Repeat([]byte{0x01}, 32),
+		PublicKey:  append([]byte{0x04}, bytes.Repeat([]byte{0x02}, 64)...),
+		ChainCode:  bytes.Repeat([]byte{0x03}, 32),
+	}
+	keyUID := bytes.Repeat([]byte{0xDD}, 32)
+
+	c := keycardio.NewMockChannel().
+		Expect(InsExportKey, exported.Serialize(), apdu.SwOK).
+		Expect(InsLoadKey, keyUID, apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	kp, err := cs.ExportKey(true, false, false, "m/43'/60'/1581'/0'/0")
+	require.NoError(t, err)
+	assert.Equal(t, exported, kp)
+
+	uid, err := cs.LoadKey(kp)
+	require.NoError(t, err)
+	assert.Equal(t, keyUID, uid)
+	assert.Equal(t, uint8(P1LoadKeyExtendedECC), c.Sent[1].P1)
+	assert.Equal(t, exported.Serialize(), c.Sent[1].Data)
+}
+
+func TestCommandSet_SetMaxCommandLength(t *testing.T) {
+	assert.Equal(t, MaxSecureDataLength, secureDataLength(MaxCommandLength))
+	assert.Equal(t, 223, secureDataLength(240))
+	assert.Equal(t, 207, secureDataLength(239))
+
+	c := newMockChannel(apdu.SwOK, apdu.SwOK)
+	cs := NewCommandSet(c)
+	assert.Equal(t, ErrInvalidMaxCommandLength, cs.SetMaxCommandLength(256))
+	assert.Equal(t, ErrInvalidMaxCommandLength, cs.SetMaxCommandLength(16))
+
+	require.NoError(t, cs.SetMaxCommandLength(200))
+	_, err := cs.StoreData(P1StoreDataPublic, make([]byte, 300))
+	require.NoError(t, err)
+	require.Len(t, c.Sent, 2)
+	assert.Len(t, c.Sent[0].Data, secureDataLength(200))
+	assert.Len(t, c.Sent[1].Data, 300-secureDataLength(200))
+}
+
+func TestCommandSet_OpenSecureChannelBadPublicKey(t *testing.T) {
+	badKey := append([]byte{0x04}, bytes.Repeat([]byte{0xFF}, 64)...)
+	c := keycardio.NewMockChannel().
+		Expect(globalplatform.InsSelect, applicationInfoResponse(nil), apdu.SwOK).
+		Expect(globalplatform.InsSelect, applicationInfoResponse(badKey), apdu.SwOK)
+	cs := NewCommandSet(c)
+	cs.SetPairingInfo(bytes.Repeat([]byte{0x01}, 32), 0)
+
+	require.NoError(t, cs.Select())
+	assert.Error(t, cs.Select())
+
+	// the secret generated for the first card can't be used
+	assert.Equal(t, ErrSecureChannelSecretNotSet, cs.OpenSecureChannel())
+	assert.Len(t, c.Sent, 2)
+}
+
+func TestCommandSet_InitializeCard(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	preInit := append([]byte{types.TagSelectResponsePreInitialized, 0x41}, ethcrypto.FromECDSAPub(&cardKey.PublicKey)...)
+
+	c := keycardio.NewMockChannel().
+		Expect(globalplatform.InsSelect, preInit, apdu.SwOK).
+		Expect(InsInit, nil, apdu.SwOK).
+		Expect(globalplatform.InsSelect, applicationInfoResponse(nil), apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	secrets, err := cs.InitializeCard()
+	require.NoError(t, err)
+	assert.Len(t, secrets.Pin(), pinLength)
+	assert.Len(t, secrets.Puk(), pukLength)
+	assert.NotEmpty(t, secrets.PairingPass())
+	assert.True(t, cs.ApplicationInfo.Initialized)
+
+	c = keycardio.NewMockChannel().Respond(applicationInfoResponse(nil), apdu.SwOK)
+	cs = NewCommandSet(c)
+	_, err = cs.InitializeCard()
+	assert.Equal(t, ErrAlreadyInitialized, err)
+	assert.Len(t, c.Sent, 1)
+
+	c = keycardio.NewMockChannel().
+		Expect(globalplatform.InsSelect, preInit, apdu.SwOK).
+		Expect(InsInit, nil, apdu.SwOK).
+		Expect(globalplatform.InsSelect, preInit, apdu.SwOK)
+	cs = NewCommandSet(c)
+	_, err = cs.InitializeCard()
+	assert.Equal(t, ErrInitFailed, err)
+}
+
+type closingChannel struct {
+	*keycardio.MockChannel
+	closed bool
+}
+
+func (c *closingChannel) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestCommandSet_Disconnect(t *testing.T) {
+	c := &closingChannel{MockChannel: newMockChannel()}
+	cs := NewCommandSet(c)
+	cs.sc = newTestSecureChannel(c)
+
+	require.NoError(t, cs.Disconnect())
+	assert.True(t, c.closed)
+	assert.False(t, cs.sc.open)
+
+	require.NoError(t, NewCommandSet(newMockChannel()).Disconnect())
+}
+
+func TestCommandSet_SignPinless(t *testing.T) {
+	hash := bytes.Repeat([]byte{0x02}, 32)
+	resp, sig := signResponse(t, hash)
+	c := keycardio.NewMockChannel().
+		Respond(resp, apdu.SwOK).
+		Respond(nil, SwReferencedDataNotFound)
+	cs := NewCommandSet(c)
+
+	s, err := cs.SignPinless(hash)
+	require.NoError(t, err)
+	assert.Equal(t, sig[:32], s.R())
+	assert.Equal(t, uint8(P1SignPinless), c.Sent[0].P1)
+	assert.Equal(t, hash, c.Sent[0].Data)
+
+	_, err = cs.SignPinless(hash)
+	assert.Equal(t, ErrPinlessPathNotSet, err)
+}
+
+func TestCommandSet_ConcurrentSign(t *testing.T) {
+	hash := bytes.Repeat([]byte{0x03}, 32)
+	resp, sig := signResponse(t, hash)
+
+	cs := NewCommandSet(nil)
+	cs.sc = newTestSecureChannel(nil)
+	card := &fakeCard{
+		encKey:   cs.sc.encKey,
+		macKey:   cs.sc.macKey,
+		response: append(resp, 0x90, 0x00),
+	}
+	cs.c = newContextChannel(card)
+	cs.sc.c = cs.c
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := cs.Sign(hash)
+			if err == nil && !bytes.Equal(sig[:32], s.R()) {
+				err = ErrMalformedResponse
+			}
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestCommandSet_PinlessPath(t *testing.T) {
+	c := newMockChannel(apdu.SwOK, 0x6A80, apdu.SwOK, apdu.SwOK)
+	cs := NewCommandSet(c)
+	assert.Empty(t, cs.PinlessPath())
+
+	require.NoError(t, cs.SetPinlessPath("m/44'/60'/0'/0/0"))
+	assert.Equal(t, "m/44'/60'/0'/0/0", cs.PinlessPath())
+
+	// rejected by the card, the previous path is kept
+	assert.Error(t, cs.SetPinlessPath("m/1"))
+	assert.Equal(t, "m/44'/60'/0'/0/0", cs.PinlessPath())
+
+	require.NoError(t, cs.SetPinlessPath(""))
+	assert.Empty(t, cs.PinlessPath())
+
+	require.NoError(t, cs.SetPinlessPath("m/1"))
+	c.Respond(nil, apdu.SwOK)
+	require.NoError(t, cs.RemoveKey(false))
+	assert.Empty(t, cs.PinlessPath())
+}
+
+func TestCommandSet_RemoveKey(t *testing.T) {
+	c := keycardio.NewMockChannel().
+		Expect(InsRemoveKey, nil, apdu.SwOK).
+		Expect(globalplatform.InsSelect, applicationInfoResponse(nil), apdu.SwOK)
+	cs := NewCommandSet(c)
+	cs.ApplicationInfo.KeyUID = bytes.Repeat([]byte{0xBB}, 32)
+
+	require.NoError(t, cs.RemoveKey(true))
+	assert.Empty(t, cs.ApplicationInfo.KeyUID)
+	assert.Len(t, c.Sent, 2)
+	assert.Zero(t, c.Pending())
+}
+
+func TestCommandSet_RemoveKeyNotRemoved(t *testing.T) {
+	keyUID := bytes.Repeat([]byte{0xBB}, 32)
+	appInfo := applicationInfoResponse(nil)
+	// replace the empty key UID closing the template
+	appInfo = append(appInfo[:len(appInfo)-1], byte(len(keyUID)))
+	appInfo = append(appInfo, keyUID...)
+	appInfo[2] += byte(len(keyUID))
+
+	c := keycardio.NewMockChannel().
+		Expect(InsRemoveKey, nil, apdu.SwOK).
+		Expect(globalplatform.InsSelect, appInfo, apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	assert.Equal(t, ErrKeyNotRemoved, cs.RemoveKey(true))
+	assert.Equal(t, keyUID, cs.ApplicationInfo.KeyUID)
+}
+
+func TestCommandSet_DeriveKey(t *testing.T) {
+	c := newMockChannel(apdu.SwOK, apdu.SwOK, apdu.SwOK, apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	paths := []string{"m/44'/60'/0'/0/0", "../1", "./2", "3"}
+	for _, path := range paths {
+		require.NoError(t, cs.DeriveKey(path))
+	}
+
+	assert.Equal(t, uint8(P1DeriveKeyFromMaster), c.Sent[0].P1)
+	assert.Equal(t, uint8(P1DeriveKeyFromParent), c.Sent[1].P1)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01}, c.Sent[1].Data)
+	assert.Equal(t, uint8(P1DeriveKeyFromCurrent), c.Sent[2].P1)
+	assert.Equal(t, uint8(P1DeriveKeyFromCurrent), c.Sent[3].P1)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x03}, c.Sent[3].Data)
+
+	assert.Error(t, cs.DeriveKey("m/a"))
+	assert.Len(t, c.Sent, 4)
+}
+
+func TestCommandSet_IsBlocked(t *testing.T) {
+	c := keycardio.NewMockChannel().
+		Respond(hexutils.HexToBytes("A3 09 02 01 00 02 01 05 01 01 00"), apdu.SwOK).
+		Respond(hexutils.HexToBytes("A3 09 02 01 00 02 01 00 01 01 00"), apdu.SwOK).
+		Respond(nil, SwConditionsNotSatisfied)
+	cs := NewCommandSet(c)
+	assert.False(t, cs.IsInitialized())
+	cs.ApplicationInfo.Initialized = true
+	assert.True(t, cs.IsInitialized())
+
+	blocked, err := cs.IsBlocked()
+	require.NoError(t, err)
+	assert.False(t, blocked)
+
+	blocked, err = cs.IsBlocked()
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	_, err = cs.IsBlocked()
+	assert.Equal(t, ErrConditionsNotSatisfied, err)
+}
@@ -1,13 +1,12 @@
 package keycard
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
 
 	"github.com/status-im/keycard-go/apdu"
 	"github.com/status-im/keycard-go/derivationpath"
 	"github.com/status-im/keycard-go/globalplatform"
+	"github.com/status-im/keycard-go/types"
 )
 
 const (
@@ -30,6 +29,8 @@ const (
 	InsLoadKey              = 0xD0
 	InsGenerateMnemonic     = 0xD2
 	InsStoreData            = 0xE2
+	InsFactoryReset         = 0xFD
+	InsIdentify             = 0x14
 
 	P1PairingFirstStep              = 0x00
 	P1PairingFinalStep              = 0x01
@@ -53,11 +54,73 @@ const (
 	P1ExportKeyDeriveAndMakeCurrent = 0x02
 	P2ExportKeyPrivateAndPublic     = 0x00
 	P2ExportKeyPublicOnly           = 0x01
+	P2ExportKeyExtendedPublic       = 0x02
+	P1LoadKeyECC                    = 0x01
+	P1LoadKeyExtendedECC            = 0x02
 	P1LoadKeySeed                   = 0x03
-
-	SwNoAvailablePairingSlots = 0x6A84
+	P1FactoryResetMagic             = 0xAA
+	P2FactoryResetMagic             = 0x55
+
+	// MaxPairingSlots is the number of pairing slots available on the card.
+	MaxPairingSlots = 5
+
+	// MaxCommandLength is the longest data of a short APDU command.
+	MaxCommandLength = 255
+
+	// minCommandLength fits the MAC and one block of encrypted data.
+	minCommandLength = 32
+
+	// MaxSecureDataLength is the longest plain text that fits in a secure channel command:
+	// 255 bytes minus the 16 bytes MAC, padded to the 16 bytes AES block size.
+	// Longer data is sent chaining multiple commands.
+	MaxSecureDataLength = 223
+
+	// MaxStoreDataLength is the longest data record, since the applet uses signed 16 bits lengths.
+	MaxStoreDataLength = 0x7FFF
+
+	SwSecurityConditionNotSatisfied = 0x6982
+	SwFileNotFound                  = 0x6A82
+	SwNoAvailablePairingSlots       = 0x6A84
+	SwWrongCredentials              = 0x63C0
+	SwConditionsNotSatisfied        = 0x6985
+	SwInsNotSupported               = 0x6D00
+	SwReferencedDataNotFound        = 0x6A88
+	SwIncorrectP1P2                 = 0x6A86
 )
 
+// RetryableCommand returns false for the commands that can't be safely executed twice,
+// because they consume PIN attempts or pairing slots or replace the card keys.
+// It can be used as keycardio.RetryChannel.Retryable.
+func RetryableCommand(cmd *apdu.Command) bool {
+	switch cmd.Ins {
+	case InsInit, InsPair, InsMutuallyAuthenticate, InsVerifyPIN, InsUnblockPIN,
+		InsGenerateKey, InsLoadKey, InsFactoryReset:
+		return false
+	default:
+		return true
+	}
+}
+
+func NewCommandFactoryReset() *apdu.Command {
+	return apdu.NewCommand(
+		globalplatform.ClaGp,
+		InsFactoryReset,
+		P1FactoryResetMagic,
+		P2FactoryResetMagic,
+		[]byte{},
+	)
+}
+
+func NewCommandIdentify(challenge []byte) *apdu.Command {
+	return apdu.NewCommand(
+		globalplatform.ClaGp,
+		InsIdentify,
+		0,
+		0,
+		challenge,
+	)
+}
+
 func NewCommandInit(data []byte) *apdu.Command {
 	return apdu.NewCommand(
 		globalplatform.ClaGp,
@@ -218,6 +281,21 @@ func NewCommandLoadSeed(seed []byte) *apdu.Command {
 	)
 }
 
+func NewCommandLoadKey(keyPair *types.KeyPair) *apdu.Command {
+	p1 := uint8(P1LoadKeyECC)
+	if keyPair.IsExtended() {
+		p1 = P1LoadKeyExtendedECC
+	}
+
+	return apdu.NewCommand(
+		globalplatform.ClaGp,
+		InsLoadKey,
+		p1,
+		0,
+		keyPair.Serialize(),
+	)
+}
+
 func NewCommandDeriveKey(pathStr string) (*apdu.Command, error) {
 	startingPoint, path, err := derivationpath.Decode(pathStr)
 	if err != nil {
@@ -229,19 +307,14 @@ func NewCommandDeriveKey(pathStr string) (*apdu.Command, error) {
 		return nil, err
 	}
 
-	data := new(bytes.Buffer)
-	for _, segment := range path {
-		if err := binary.Write(data, binary.BigEndian, segment); err != nil {
-			return nil, err
-		}
-	}
+	data := derivationpath.EncodeToBytes(path)
 
 	return apdu.NewCommand(
 		globalplatform.ClaGp,
 		InsDeriveKey,
 		p1,
 		0,
-		data.Bytes(),
+		data,
 	), nil
 }
 
@@ -254,6 +327,7 @@ func NewCommandDeriveKey(pathStr string) (*apdu.Command, error) {
 //	 @param {p2}
 //			0x00: return public and private key pair
 //			0x01: return only the public key
+//			0x02: return the public key and the chain code
 //	 @param {pathStr}
 //			Derivation path of format "m/x/x/x/x/x", e.g. "m/44'/0'/0'/0/0"
 func NewCommandExportKey(p1 uint8, p2 uint8, pathStr string) (*apdu.Command, error) {
@@ -267,19 +341,14 @@ func NewCommandExportKey(p1 uint8, p2 uint8, pathStr string) (*apdu.Command, err
 		return nil, err
 	}
 
-	data := new(bytes.Buffer)
-	for _, segment := range path {
-		if err := binary.Write(data, binary.BigEndian, segment); err != nil {
-			return nil, err
-		}
-	}
+	data := derivationpath.EncodeToBytes(path)
 
 	return apdu.NewCommand(
 		globalplatform.ClaGp,
 		InsExportKey,
 		p1|deriveP1,
 		p2,
-		data.Bytes(),
+		data,
 	), nil
 }
 
@@ -293,19 +362,14 @@ func NewCommandSetPinlessPath(pathStr string) (*apdu.Command, error) {
 		return nil, fmt.Errorf("pinless path must be set with an absolute path")
 	}
 
-	data := new(bytes.Buffer)
-	for _, segment := range path {
-		if err := binary.Write(data, binary.BigEndian, segment); err != nil {
-			return nil, err
-		}
-	}
+	data := derivationpath.EncodeToBytes(path)
 
 	return apdu.NewCommand(
 		globalplatform.ClaGp,
 		InsSetPinlessPath,
 		0,
 		0,
-		data.Bytes(),
+		data,
 	), nil
 }
 
@@ -315,19 +379,18 @@ func NewCommandSign(data []byte, p1 uint8, pathStr string) (*apdu.Command, error
 	}
 
 	if p1 == P1SignDerive || p1 == P1SignDeriveAndMakeCurrent {
-		_, path, err := derivationpath.Decode(pathStr)
+		startingPoint, path, err := derivationpath.Decode(pathStr)
 		if err != nil {
 			return nil, err
 		}
 
-		pathData := new(bytes.Buffer)
-		for _, segment := range path {
-			if err := binary.Write(pathData, binary.BigEndian, segment); err != nil {
-				return nil, err
-			}
+		deriveP1, err := derivationP1FromStartingPoint(startingPoint)
+		if err != nil {
+			return nil, err
 		}
 
-		data = append(data, pathData.Bytes()...)
+		p1 |= deriveP1
+		data = append(append([]byte{}, data...), derivationpath.EncodeToBytes(path)...)
 	}
 
 	return apdu.NewCommand(
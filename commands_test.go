@@ -0,0 +1,105 @@
+package keycard
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCommandDeriveKey(t *testing.T) {
+	scenarios := []struct {
+		path         string
+		expectedP1   uint8
+		expectedData string
+		err          error
+	}{
+		{
+			path:         "m",
+			expectedP1:   P1DeriveKeyFromMaster,
+			expectedData: "",
+		},
+		{
+			path:         "m/44'/60'/0'/0/0",
+			expectedP1:   P1DeriveKeyFromMaster,
+			expectedData: "8000002C8000003C800000000000000000000000",
+		},
+		{
+			path:         "../1",
+			expectedP1:   P1DeriveKeyFromParent,
+			expectedData: "00000001",
+		},
+		{
+			path:         "./2'",
+			expectedP1:   P1DeriveKeyFromCurrent,
+			expectedData: "80000002",
+		},
+		{
+			path: "m/44'/a",
+			err:  fmt.Errorf("at position 7, expected number, got a"),
+		},
+		{
+			path: "m/2147483648'",
+			err:  fmt.Errorf("at position 3, index must be lower than 2^31, got 2147483648"),
+		},
+	}
+
+	for i, s := range scenarios {
+		t.Run(fmt.Sprintf("scenario %d", i), func(t *testing.T) {
+			cmd, err := NewCommandDeriveKey(s.path)
+			if s.err != nil {
+				assert.Equal(t, s.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, uint8(InsDeriveKey), cmd.Ins)
+			assert.Equal(t, s.expectedP1, cmd.P1)
+			assert.Equal(t, s.expectedData, hexutils.BytesToHex(cmd.Data))
+		})
+	}
+}
+
+func TestNewCommandSetPinlessPath(t *testing.T) {
+	cmd, err := NewCommandSetPinlessPath("m/44'/60'/0'/0/0")
+	require.NoError(t, err)
+	assert.Equal(t, uint8(InsSetPinlessPath), cmd.Ins)
+	assert.Equal(t, "8000002C8000003C800000000000000000000000", hexutils.BytesToHex(cmd.Data))
+
+	cmd, err = NewCommandSetPinlessPath("")
+	require.NoError(t, err)
+	assert.Empty(t, cmd.Data)
+
+	_, err = NewCommandSetPinlessPath("../0")
+	assert.Error(t, err)
+}
+
+func TestNewCommandSign(t *testing.T) {
+	hash := bytes.Repeat([]byte{0xAA}, 32)
+
+	_, err := NewCommandSign(hash[:31], P1SignCurrentKey, "")
+	assert.Error(t, err)
+
+	cmd, err := NewCommandSign(hash, P1SignCurrentKey, "")
+	require.NoError(t, err)
+	assert.Equal(t, hash, cmd.Data)
+
+	cmd, err = NewCommandSign(hash, P1SignDerive, "m/44'/0")
+	require.NoError(t, err)
+	assert.Equal(t, uint8(P1SignDerive|P1DeriveKeyFromMaster), cmd.P1)
+	assert.Equal(t, hexutils.BytesToHex(hash)+"8000002C00000000", hexutils.BytesToHex(cmd.Data))
+
+	cmd, err = NewCommandSign(hash, P1SignDeriveAndMakeCurrent, "./1")
+	require.NoError(t, err)
+	assert.Equal(t, uint8(P1SignDeriveAndMakeCurrent|P1DeriveKeyFromCurrent), cmd.P1)
+	assert.Len(t, hash, 32)
+}
+
+func TestRetryableCommand(t *testing.T) {
+	assert.False(t, RetryableCommand(NewCommandGenerateKey()))
+	assert.False(t, RetryableCommand(NewCommandVerifyPIN("123456")))
+	assert.True(t, RetryableCommand(NewCommandGetStatus(P1GetStatusApplication)))
+}
@@ -0,0 +1,151 @@
+package keycard
+
+import (
+	"errors"
+
+	"github.com/status-im/keycard-go/types"
+)
+
+// ConnectionState is how far Connect went in setting up a card.
+type ConnectionState int
+
+const (
+	// StateUnknown is returned with errors preventing to know the card state, like a failed Select.
+	StateUnknown ConnectionState = iota
+	// StatePreInitialized means the card must be initialized with Init.
+	StatePreInitialized
+	// StateNeedsPairing means the client must pair with the card, or the pairing it has is no longer valid.
+	StateNeedsPairing
+	// StateReady means the secure channel is open.
+	StateReady
+	// StateBlocked means the PIN is blocked and must be reset with UnblockPIN.
+	StateBlocked
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StatePreInitialized:
+		return "PRE_INITIALIZED"
+	case StateNeedsPairing:
+		return "NEEDS_PAIRING"
+	case StateReady:
+		return "READY"
+	case StateBlocked:
+		return "BLOCKED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Connect selects the applet and goes as far as it can towards an open secure channel
+// with the given secrets and pairing, both optional:
+//   - a pre-initialized card is initialized with secrets;
+//   - the secure channel is opened with pairing or, if nil, after pairing with the secrets pairing password;
+//   - the PIN is verified with the secrets PIN.
+//
+// It returns the state the card was left in, so the caller knows which step is missing.
+// When the card was paired, the new pairing is in PairingInfo and should be stored.
+func (cs *CommandSet) Connect(secrets *Secrets, pairing *types.PairingInfo) (ConnectionState, error) {
+	if err := cs.Select(); err != nil {
+		return StateUnknown, err
+	}
+
+	return cs.connect(secrets, pairing)
+}
+
+// ConnectWithStore is like Connect, reusing the pairing stored for the card InstanceUID.
+// Without a stored pairing, the card is paired with the secrets pairing password and the new
+// pairing is stored. A stored pairing the card rejects is replaced once the card is paired again,
+// and kept if pairing fails or if the secure channel couldn't be opened for another reason.
+func (cs *CommandSet) ConnectWithStore(store PairingStore, secrets *Secrets) (ConnectionState, error) {
+	if err := cs.Select(); err != nil {
+		return StateUnknown, err
+	}
+
+	// the instance UID is generated by INIT
+	if !cs.ApplicationInfo.Initialized {
+		if secrets == nil {
+			return StatePreInitialized, nil
+		}
+
+		// the applet was just selected
+		if _, err := cs.Init(secrets, true); err != nil {
+			return StatePreInitialized, err
+		}
+	}
+
+	instanceUID := cs.ApplicationInfo.InstanceUID
+	pairing, err := store.Get(instanceUID)
+	if err != nil && err != ErrPairingNotFound {
+		return StateNeedsPairing, err
+	}
+
+	if pairing != nil {
+		state, err := cs.connect(secrets, pairing)
+		if state != StateNeedsPairing || !isPairingRejected(err) {
+			return state, err
+		}
+	}
+
+	state, err := cs.connect(secrets, nil)
+	if cs.PairingInfo != nil && state != StateNeedsPairing {
+		if putErr := store.Put(instanceUID, cs.PairingInfo); putErr != nil && err == nil {
+			err = putErr
+		}
+	}
+
+	return state, err
+}
+
+// isPairingRejected returns true if opening the secure channel failed because the pairing
+// was removed or replaced on the card.
+func isPairingRejected(err error) bool {
+	return errors.Is(err, ErrPairingInvalid) || errors.Is(err, ErrInvalidResponseMAC)
+}
+
+// connect goes on from Connect once the applet is selected.
+func (cs *CommandSet) connect(secrets *Secrets, pairing *types.PairingInfo) (ConnectionState, error) {
+	if !cs.ApplicationInfo.Initialized {
+		if secrets == nil {
+			return StatePreInitialized, nil
+		}
+
+		// the applet was just selected
+		if _, err := cs.Init(secrets, true); err != nil {
+			return StatePreInitialized, err
+		}
+	}
+
+	if pairing != nil {
+		cs.SetPairingInfo(pairing.Key, pairing.Index)
+	} else if secrets != nil {
+		if err := cs.Pair(secrets.PairingPass()); err != nil {
+			return StateNeedsPairing, err
+		}
+	} else {
+		return StateNeedsPairing, nil
+	}
+
+	if err := cs.OpenSecureChannel(); err != nil {
+		return StateNeedsPairing, err
+	}
+
+	retries, err := cs.PINRetryCount()
+	if err != nil {
+		return StateReady, err
+	}
+
+	if retries == 0 {
+		return StateBlocked, nil
+	}
+
+	if secrets == nil {
+		return StateReady, nil
+	}
+
+	if err = cs.VerifyPIN(secrets.Pin()); err == ErrPINBlocked {
+		return StateBlocked, err
+	}
+
+	return StateReady, err
+}
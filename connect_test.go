@@ -0,0 +1,134 @@
+package keycard
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/globalplatform"
+	"github.com/status-im/keycard-go/hexutils"
+	keycardio "github.com/status-im/keycard-go/io"
+	"github.com/status-im/keycard-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandSet_Connect(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+
+	preInit := append([]byte{types.TagSelectResponsePreInitialized, 0x41}, ethcrypto.FromECDSAPub(&cardKey.PublicKey)...)
+	cs := NewCommandSet(keycardio.NewMockChannel().Respond(preInit, apdu.SwOK))
+	state, err := cs.Connect(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, StatePreInitialized, state)
+
+	card := &fakeCard{key: cardKey, pairingKey: bytes.Repeat([]byte{0x01}, 32)}
+	cs = NewCommandSet(card)
+	state, err = cs.Connect(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, StateNeedsPairing, state)
+
+	pairing := &types.PairingInfo{Key: card.pairingKey, Index: 1}
+	card.response = hexutils.HexToBytes("A3 09 02 01 03 02 01 05 01 01 00 90 00")
+	secrets, err := NewSecrets("123456", "123456789012", "KeycardTest")
+	require.NoError(t, err)
+	state, err = cs.Connect(secrets, pairing)
+	require.NoError(t, err)
+	assert.Equal(t, StateReady, state)
+	assert.Equal(t, "READY", state.String())
+
+	card.response = hexutils.HexToBytes("A3 09 02 01 00 02 01 05 01 01 00 90 00")
+	state, err = cs.Connect(secrets, pairing)
+	require.NoError(t, err)
+	assert.Equal(t, StateBlocked, state)
+
+	state, err = cs.Connect(nil, &types.PairingInfo{Key: bytes.Repeat([]byte{0x02}, 32), Index: 1})
+	assert.Equal(t, ErrPairingInvalid, err)
+	assert.Equal(t, StateNeedsPairing, state)
+
+	cs = NewCommandSet(newMockChannel(SwFileNotFound))
+	state, err = cs.Connect(nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, StateUnknown, state)
+}
+
+func TestCommandSet_ConnectWithStore(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	card := &fakeCard{key: cardKey, pairingPass: "KeycardTest"}
+	card.response = hexutils.HexToBytes("A3 09 02 01 03 02 01 05 01 01 00 90 00")
+	store := NewFilePairingStore(filepath.Join(t.TempDir(), "pairings.json"))
+	secrets, err := NewSecrets("123456", "123456789012", "KeycardTest")
+	require.NoError(t, err)
+
+	state, err := NewCommandSet(card).ConnectWithStore(store, nil)
+	require.NoError(t, err)
+	assert.Equal(t, StateNeedsPairing, state)
+
+	cs := NewCommandSet(card)
+	state, err = cs.ConnectWithStore(store, secrets)
+	require.NoError(t, err)
+	assert.Equal(t, StateReady, state)
+
+	stored, err := store.Get(cs.ApplicationInfo.InstanceUID)
+	require.NoError(t, err)
+	assert.Equal(t, &types.PairingInfo{Key: card.pairingKey, Index: 1}, stored)
+
+	// the stored pairing is reused, the card can't pair anymore
+	card.pairingPass = ""
+	cs = NewCommandSet(card)
+	state, err = cs.ConnectWithStore(store, secrets)
+	require.NoError(t, err)
+	assert.Equal(t, StateReady, state)
+
+	// a pairing removed from the card is replaced
+	card.pairingPass = "KeycardTest"
+	require.NoError(t, store.Put(cs.ApplicationInfo.InstanceUID, &types.PairingInfo{Key: bytes.Repeat([]byte{0x02}, 32), Index: 0}))
+	state, err = NewCommandSet(card).ConnectWithStore(store, secrets)
+	require.NoError(t, err)
+	assert.Equal(t, StateReady, state)
+
+	stored, err = store.Get(cs.ApplicationInfo.InstanceUID)
+	require.NoError(t, err)
+	assert.Equal(t, card.pairingKey, stored.Key)
+}
+
+func TestCommandSet_ConnectWithStoreKeepsPairing(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	card := &fakeCard{key: cardKey, pairingKey: bytes.Repeat([]byte{0x01}, 32)}
+	store := NewFilePairingStore(filepath.Join(t.TempDir(), "pairings.json"))
+	secrets, err := NewSecrets("123456", "123456789012", "KeycardTest")
+	require.NoError(t, err)
+
+	cs := NewCommandSet(card)
+	require.NoError(t, cs.Select())
+	instanceUID := cs.ApplicationInfo.InstanceUID
+	pairing := &types.PairingInfo{Key: bytes.Repeat([]byte{0x02}, 32), Index: 1}
+	require.NoError(t, store.Put(instanceUID, pairing))
+
+	// the card rejects the pairing and can't pair again
+	state, err := NewCommandSet(card).ConnectWithStore(store, secrets)
+	assert.Error(t, err)
+	assert.Equal(t, StateNeedsPairing, state)
+
+	stored, err := store.Get(instanceUID)
+	require.NoError(t, err)
+	assert.Equal(t, pairing, stored)
+
+	// OPEN SECURE CHANNEL fails for another reason
+	c := keycardio.NewMockChannel().
+		Expect(globalplatform.InsSelect, applicationInfoResponse(ethcrypto.FromECDSAPub(&cardKey.PublicKey)), apdu.SwOK).
+		Expect(InsOpenSecureChannel, nil, 0x6A80)
+	state, err = NewCommandSet(c).ConnectWithStore(store, secrets)
+	assert.Error(t, err)
+	assert.Equal(t, StateNeedsPairing, state)
+	assert.Zero(t, c.Pending())
+
+	stored, err = store.Get(instanceUID)
+	require.NoError(t, err)
+	assert.Equal(t, pairing, stored)
+}
@@ -0,0 +1,66 @@
+package keycard
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/types"
+)
+
+// contextChannel wraps a Channel and stops sending commands once its context is done.
+// An interrupted command keeps running in the background: the next command waits for it
+// to complete, or for its own context to be done, so the wrapped channel never sends two
+// commands at once.
+type contextChannel struct {
+	c    types.Channel
+	ctx  context.Context
+	busy chan struct{}
+}
+
+func newContextChannel(c types.Channel) *contextChannel {
+	return &contextChannel{
+		c:    c,
+		ctx:  context.Background(),
+		busy: make(chan struct{}, 1),
+	}
+}
+
+type sendResult struct {
+	resp *apdu.Response
+	err  error
+}
+
+// Send checks the context before sending cmd and returns as soon as the context is done,
+// even if the inner channel has not responded yet.
+func (c *contextChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	ctx := c.ctx
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("command %02X not sent: %w", cmd.Ins, err)
+	}
+
+	select {
+	case c.busy <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("command %02X not sent: %w", cmd.Ins, ctx.Err())
+	}
+
+	if ctx.Done() == nil {
+		defer func() { <-c.busy }()
+		return c.c.Send(cmd)
+	}
+
+	result := make(chan sendResult, 1)
+	go func() {
+		resp, err := c.c.Send(cmd)
+		<-c.busy
+		result <- sendResult{resp, err}
+	}()
+
+	select {
+	case r := <-result:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("command %02X interrupted: %w", cmd.Ins, ctx.Err())
+	}
+}
@@ -0,0 +1,80 @@
+package keycard
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type blockingChannel struct {
+	release chan struct{}
+	sent    int32
+}
+
+func (bc *blockingChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	atomic.AddInt32(&bc.sent, 1)
+	<-bc.release
+	return &apdu.Response{Sw: apdu.SwOK}, nil
+}
+
+func TestCommandSet_SetContext(t *testing.T) {
+	c := newMockChannel(apdu.SwOK)
+	cs := NewCommandSet(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cs.SetContext(ctx)
+	require.NoError(t, cs.VerifyPIN("123456"))
+
+	cancel()
+	err := cs.VerifyPIN("123456")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Len(t, c.Sent, 1)
+
+	cs.SetContext(nil)
+	c.Respond(nil, apdu.SwOK)
+	require.NoError(t, cs.VerifyPIN("123456"))
+}
+
+func TestCommandSet_SetContext_Interrupted(t *testing.T) {
+	c := &blockingChannel{release: make(chan struct{})}
+	defer close(c.release)
+	cs := NewCommandSet(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cs.SetContext(ctx)
+
+	err := cs.VerifyPIN("123456")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "command 20 interrupted")
+}
+
+func TestCommandSet_SetContext_WaitsForInterrupted(t *testing.T) {
+	c := &blockingChannel{release: make(chan struct{})}
+	cs := NewCommandSet(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cs.SetContext(ctx)
+	assert.True(t, errors.Is(cs.VerifyPIN("123456"), context.DeadlineExceeded))
+
+	// the interrupted command still holds the channel
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cs.SetContext(ctx)
+	err := cs.VerifyPIN("123456")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "command 20 not sent")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&c.sent))
+
+	close(c.release)
+	cs.SetContext(nil)
+	require.NoError(t, cs.VerifyPIN("123456"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&c.sent))
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/sha512"
@@ -17,15 +18,62 @@ import (
 
 const PairingTokenSalt = "Keycard Pairing Password Salt"
 
+// PairingTokenIterations is the PBKDF2 iteration count the applet uses to derive the pairing token.
+const PairingTokenIterations = 50000
+
+const (
+	// SessionSaltLength is the length of the salt in the OPEN SECURE CHANNEL response.
+	SessionSaltLength = 32
+	// SessionIVLength is the length of the initial IV following the salt, one AES block.
+	SessionIVLength = 16
+)
+
+// ErrInvalidCardCryptogram is returned when the card cryptogram is malformed.
 var ErrInvalidCardCryptogram = errors.New("invalid card cryptogram")
 
+// ErrWrongPairingPassword is returned when the card cryptogram doesn't match the pairing password.
+var ErrWrongPairingPassword = errors.New("wrong pairing password")
+var ErrInvalidCiphertextLength = errors.New("ciphertext length must be a non zero multiple of the block size")
+var ErrInvalidMacDataLength = errors.New("MAC data must be at least 16 bytes once padded to two blocks")
+var ErrInvalidSessionData = errors.New("secure channel session data must be a 32 bytes salt and a 16 bytes iv")
+
+// zeroIV is the IV of MAC calculations, only read by the CBC encrypter.
+var zeroIV = make([]byte, aes.BlockSize)
+
+// Curve returns secp256k1, the curve of the card keys and of the secure channel ECDH.
+// The P-256 curve of the standard library is never used.
+func Curve() elliptic.Curve {
+	return crypto.S256()
+}
+
+// GenerateECDHSharedSecret returns the x coordinate of the ECDH point on Curve,
+// as the card computes the secure channel secret.
 func GenerateECDHSharedSecret(priv *ecdsa.PrivateKey, pub *ecdsa.PublicKey) []byte {
-	x, _ := crypto.S256().ScalarMult(pub.X, pub.Y, priv.D.Bytes())
+	x, _ := Curve().ScalarMult(pub.X, pub.Y, priv.D.Bytes())
 	return x.FillBytes(make([]byte, 32))
 }
 
+// GeneratePairingToken derives the pairing token from pairingPass with iterations rounds
+// of PBKDF2-SHA256, normally PairingTokenIterations.
+func GeneratePairingToken(pairingPass string, iterations int) []byte {
+	return pbkdf2.Key(norm.NFKD.Bytes([]byte(pairingPass)), norm.NFKD.Bytes([]byte(PairingTokenSalt)), iterations, 32, sha256.New)
+}
+
+// VerifyCryptogram checks that the card computed cardCryptogram as the sha256 of the pairing token
+// derived from pairingPass and challenge, proving that both sides know the pairing password.
+// It returns the pairing token, or ErrWrongPairingPassword if the cryptogram doesn't match.
 func VerifyCryptogram(challenge []byte, pairingPass string, cardCryptogram []byte) ([]byte, error) {
-	secretHash := pbkdf2.Key(norm.NFKD.Bytes([]byte(pairingPass)), norm.NFKD.Bytes([]byte(PairingTokenSalt)), 50000, 32, sha256.New)
+	return VerifyCryptogramWithIterations(challenge, pairingPass, PairingTokenIterations, cardCryptogram)
+}
+
+// VerifyCryptogramWithIterations is like VerifyCryptogram, deriving the pairing token
+// with the given PBKDF2 iteration count.
+func VerifyCryptogramWithIterations(challenge []byte, pairingPass string, iterations int, cardCryptogram []byte) ([]byte, error) {
+	if len(cardCryptogram) != sha256.Size {
+		return nil, ErrInvalidCardCryptogram
+	}
+
+	secretHash := GeneratePairingToken(pairingPass, iterations)
 
 	h := sha256.New()
 	h.Write(secretHash[:])
@@ -33,7 +81,7 @@ func VerifyCryptogram(challenge []byte, pairingPass string, cardCryptogram []byt
 	expectedCryptogram := h.Sum(nil)
 
 	if !bytes.Equal(expectedCryptogram, cardCryptogram) {
-		return nil, ErrInvalidCardCryptogram
+		return nil, ErrWrongPairingPassword
 	}
 
 	return secretHash, nil
@@ -64,9 +112,16 @@ func OneShotEncrypt(pubKeyData, secret, data []byte) ([]byte, error) {
 	return encrypted, nil
 }
 
-func DeriveSessionKeys(secret, pairingKey, cardData []byte) ([]byte, []byte, []byte) {
-	salt := cardData[:32]
-	iv := cardData[32:]
+// DeriveSessionKeys derives the secure channel encryption and MAC keys from the ECDH secret,
+// the pairing key and the salt sent by the card in the OPEN SECURE CHANNEL response,
+// which is followed by the initial IV.
+func DeriveSessionKeys(secret, pairingKey, cardData []byte) ([]byte, []byte, []byte, error) {
+	if len(cardData) != SessionSaltLength+SessionIVLength {
+		return nil, nil, nil, ErrInvalidSessionData
+	}
+
+	salt := cardData[:SessionSaltLength]
+	iv := cardData[SessionSaltLength:]
 
 	h := sha512.New()
 	h.Write(secret)
@@ -77,22 +132,25 @@ func DeriveSessionKeys(secret, pairingKey, cardData []byte) ([]byte, []byte, []b
 	encKey := data[:32]
 	macKey := data[32:]
 
-	return encKey, macKey, iv
+	return encKey, macKey, iv, nil
 }
 
 func EncryptData(data []byte, encKey []byte, iv []byte) ([]byte, error) {
-	data = appendPadding(16, data)
-
 	block, err := aes.NewCipher(encKey)
 	if err != nil {
 		return nil, err
 	}
 
-	ciphertext := make([]byte, len(data))
-	mode := cipher.NewCBCEncrypter(block, iv)
-	mode.CryptBlocks(ciphertext, data)
+	return EncryptDataWithCipher(data, block, iv), nil
+}
 
-	return ciphertext, nil
+// EncryptDataWithCipher is EncryptData with an AES cipher already created from the key,
+// letting a session reuse it for all its commands.
+func EncryptDataWithCipher(data []byte, block cipher.Block, iv []byte) []byte {
+	data = appendPadding(16, data)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+
+	return data
 }
 
 func DecryptData(data []byte, encKey []byte, iv []byte) ([]byte, error) {
@@ -101,6 +159,15 @@ func DecryptData(data []byte, encKey []byte, iv []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	return DecryptDataWithCipher(data, block, iv)
+}
+
+// DecryptDataWithCipher is DecryptData with an AES cipher already created from the key.
+func DecryptDataWithCipher(data []byte, block cipher.Block, iv []byte) ([]byte, error) {
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, ErrInvalidCiphertextLength
+	}
+
 	plaintext := make([]byte, len(data))
 	mode := cipher.NewCBCDecrypter(block, iv)
 	mode.CryptBlocks(plaintext, data)
@@ -109,20 +176,28 @@ func DecryptData(data []byte, encKey []byte, iv []byte) ([]byte, error) {
 }
 
 func CalculateMac(meta []byte, data []byte, macKey []byte) ([]byte, error) {
-	data = appendPadding(16, data)
-
 	block, err := aes.NewCipher(macKey)
 	if err != nil {
 		return nil, err
 	}
 
-	mode := cipher.NewCBCEncrypter(block, make([]byte, 16))
+	return CalculateMacWithCipher(meta, data, block)
+}
+
+// CalculateMacWithCipher is CalculateMac with an AES cipher already created from the MAC key.
+// Like CalculateMac, it encrypts meta in place.
+func CalculateMacWithCipher(meta []byte, data []byte, block cipher.Block) ([]byte, error) {
+	data = appendPadding(16, data)
+	// the MAC is the second to last block, so at least two blocks are needed
+	if len(data) < 2*aes.BlockSize {
+		return nil, ErrInvalidMacDataLength
+	}
+
+	mode := cipher.NewCBCEncrypter(block, zeroIV)
 	mode.CryptBlocks(meta, meta)
 	mode.CryptBlocks(data, data)
 
-	mac := data[len(data)-32 : len(data)-16]
-
-	return mac, nil
+	return data[len(data)-32 : len(data)-16], nil
 }
 
 func appendPadding(blockSize int, data []byte) []byte {
@@ -1,11 +1,14 @@
 package crypto
 
 import (
+	"crypto/sha256"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/status-im/keycard-go/hexutils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/pbkdf2"
 )
 
 func TestECDH(t *testing.T) {
@@ -20,12 +23,35 @@ func TestECDH(t *testing.T) {
 	assert.Equal(t, sharedSecret1, sharedSecret2)
 }
 
+func TestECDH_Secp256k1(t *testing.T) {
+	// secp256k1 order, P-256 has the same size but a different order
+	assert.Equal(t, "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", hexutils.BytesToHex(Curve().Params().N.Bytes()))
+
+	one, err := crypto.ToECDSA(leftPad(1))
+	require.NoError(t, err)
+	two, err := crypto.ToECDSA(leftPad(2))
+	require.NoError(t, err)
+
+	// the public key of 1 is the secp256k1 generator, the secret of 1 and 2 is the x coordinate of 2G
+	assert.Equal(t, "79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", hexutils.BytesToHex(one.PublicKey.X.FillBytes(make([]byte, 32))))
+	assert.Equal(t, "C6047F9441ED7D6D3045406E95C07CD85C778E4B8CEF3CA7ABAC09B95C709EE5", hexutils.BytesToHex(GenerateECDHSharedSecret(one, &two.PublicKey)))
+	assert.Equal(t, "C6047F9441ED7D6D3045406E95C07CD85C778E4B8CEF3CA7ABAC09B95C709EE5", hexutils.BytesToHex(GenerateECDHSharedSecret(two, &one.PublicKey)))
+}
+
+func leftPad(d byte) []byte {
+	b := make([]byte, 32)
+	b[31] = d
+
+	return b
+}
+
 func TestDeriveSessionKeys(t *testing.T) {
 	secret := hexutils.HexToBytes("B410E816DA313545151807E25A830201FA389913A977066AB0C6DE0E8631E400")
 	pairingKey := hexutils.HexToBytes("544FF0B9B0737E4BFC4ECDFCE09F522B837051BBE4FFCEC494FA420D8525670E")
 	cardData := hexutils.HexToBytes("1D7C033E75E10EC578AB538F69F1B02538571BA3831441F1649E3F24B5B3E3E71D7BC2D6A3D02FC8CB2FBB3FD8711BB5")
 
-	encKey, macKey, iv := DeriveSessionKeys(secret, pairingKey, cardData)
+	encKey, macKey, iv, err := DeriveSessionKeys(secret, pairingKey, cardData)
+	assert.NoError(t, err)
 
 	expectedIV := "1D7BC2D6A3D02FC8CB2FBB3FD8711BB5"
 	expectedEncKey := "4FF496554C01BAE0A52323E3481B448C99D43982118D95C6918FE0354D224B90"
@@ -34,6 +60,11 @@ func TestDeriveSessionKeys(t *testing.T) {
 	assert.Equal(t, expectedIV, hexutils.BytesToHex(iv))
 	assert.Equal(t, expectedEncKey, hexutils.BytesToHex(encKey))
 	assert.Equal(t, expectedMacKey, hexutils.BytesToHex(macKey))
+
+	_, _, _, err = DeriveSessionKeys(secret, pairingKey, cardData[:32])
+	assert.Equal(t, ErrInvalidSessionData, err)
+	_, _, _, err = DeriveSessionKeys(secret, pairingKey, append(cardData, 0x00))
+	assert.Equal(t, ErrInvalidSessionData, err)
 }
 
 func TestEncryptData(t *testing.T) {
@@ -84,3 +115,61 @@ func TestRemovePadding(t *testing.T) {
 		assert.Equal(t, s.expected, hexutils.BytesToHex(res))
 	}
 }
+
+func TestVerifyCryptogram(t *testing.T) {
+	challenge := hexutils.HexToBytes("ED3F0D8C4B6B4E1A9FBC11043B1C6DF01E9E4AB3D9FC1A1F6B8B734B59F6B35D")
+	token := pbkdf2.Key([]byte("KeycardTest"), []byte(PairingTokenSalt), 50000, 32, sha256.New)
+	h := sha256.New()
+	h.Write(token)
+	h.Write(challenge)
+	cryptogram := h.Sum(nil)
+
+	secretHash, err := VerifyCryptogram(challenge, "KeycardTest", cryptogram)
+	assert.NoError(t, err)
+	assert.Equal(t, token, secretHash)
+
+	_, err = VerifyCryptogram(challenge, "WrongPassword", cryptogram)
+	assert.Equal(t, ErrWrongPairingPassword, err)
+
+	_, err = VerifyCryptogram(challenge, "KeycardTest", cryptogram[:31])
+	assert.Equal(t, ErrInvalidCardCryptogram, err)
+}
+
+func TestGeneratePairingToken(t *testing.T) {
+	token := GeneratePairingToken("KeycardTest", PairingTokenIterations)
+	assert.Equal(t, "05C6CE68C78760FD529232A37484D9420BCE348FFCF00689F03FBC5F8761723B", hexutils.BytesToHex(token))
+
+	token = GeneratePairingToken("KeycardTest", 1000)
+	assert.Equal(t, "7E5EE263100DF2487AE57D10023E3087A9C42922ED297C89436DF141988F8D86", hexutils.BytesToHex(token))
+
+	challenge := make([]byte, 32)
+	cryptogram := sha256.Sum256(append(token, challenge...))
+	_, err := VerifyCryptogram(challenge, "KeycardTest", cryptogram[:])
+	assert.Equal(t, ErrWrongPairingPassword, err)
+	secretHash, err := VerifyCryptogramWithIterations(challenge, "KeycardTest", 1000, cryptogram[:])
+	assert.NoError(t, err)
+	assert.Equal(t, token, secretHash)
+}
+
+func TestCalculateMac_InvalidLength(t *testing.T) {
+	macKey := make([]byte, 32)
+	for _, n := range []int{0, 1, 15} {
+		_, err := CalculateMac(make([]byte, 16), make([]byte, n), macKey)
+		assert.Equal(t, ErrInvalidMacDataLength, err, n)
+	}
+
+	mac, err := CalculateMac(make([]byte, 16), make([]byte, 16), macKey)
+	assert.NoError(t, err)
+	assert.Len(t, mac, 16)
+}
+
+func TestDecryptData_InvalidLength(t *testing.T) {
+	encKey := hexutils.HexToBytes("D93D8E6164196D5C5B5F84F10E4B90D98F8D282ED145513ED666AA55C9871E79")
+	iv := hexutils.HexToBytes("F959B1220333046D3C47D61B1E1B891B")
+
+	_, err := DecryptData([]byte{}, encKey, iv)
+	assert.Equal(t, ErrInvalidCiphertextLength, err)
+
+	_, err = DecryptData(make([]byte, 17), encKey, iv)
+	assert.Equal(t, ErrInvalidCiphertextLength, err)
+}
@@ -0,0 +1,29 @@
+package crypto_test
+
+import (
+	"fmt"
+
+	"github.com/status-im/keycard-go/crypto"
+	"github.com/status-im/keycard-go/hexutils"
+)
+
+// The session keys derived from a known secret, pairing key and OPEN SECURE CHANNEL response,
+// to check other implementations against.
+func ExampleDeriveSessionKeys() {
+	secret := hexutils.HexToBytes("B410E816DA313545151807E25A830201FA389913A977066AB0C6DE0E8631E400")
+	pairingKey := hexutils.HexToBytes("544FF0B9B0737E4BFC4ECDFCE09F522B837051BBE4FFCEC494FA420D8525670E")
+	cardData := hexutils.HexToBytes("1D7C033E75E10EC578AB538F69F1B02538571BA3831441F1649E3F24B5B3E3E71D7BC2D6A3D02FC8CB2FBB3FD8711BB5")
+
+	encKey, macKey, iv, err := crypto.DeriveSessionKeys(secret, pairingKey, cardData)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("enc:", hexutils.BytesToHex(encKey))
+	fmt.Println("mac:", hexutils.BytesToHex(macKey))
+	fmt.Println("iv: ", hexutils.BytesToHex(iv))
+	// Output:
+	// enc: 4FF496554C01BAE0A52323E3481B448C99D43982118D95C6918FE0354D224B90
+	// mac: 185811013138EA1B4FFDBBFA7343EF2DBE3E54C2C231885E867F792448AC2FE5
+	// iv:  1D7BC2D6A3D02FC8CB2FBB3FD8711BB5
+}
@@ -34,6 +34,7 @@ type decoder struct {
 	start                StartingPoint
 	currentToken         string
 	currentTokenHardened bool
+	currentTokenPos      int
 }
 
 func newDecoder(path string) *decoder {
@@ -58,6 +59,7 @@ func (d *decoder) reset() {
 func (d *decoder) resetCurrentToken() {
 	d.currentToken = ""
 	d.currentTokenHardened = false
+	d.currentTokenPos = 0
 }
 
 func (d *decoder) parse() (StartingPoint, []uint32, error) {
@@ -139,7 +141,7 @@ func (d *decoder) saveSegment() error {
 		}
 
 		if i >= hardenedStart {
-			d.pos -= len(d.currentToken) - 1
+			d.pos = d.currentTokenPos
 			return fmt.Errorf("index must be lower than 2^31, got %d", i)
 		}
 
@@ -158,6 +160,14 @@ func (d *decoder) saveSegment() error {
 
 func (d *decoder) parseSeparator() error {
 	b, err := d.readByte()
+	if err == io.EOF {
+		if newErr := d.saveSegment(); newErr != nil {
+			return newErr
+		}
+
+		return err
+	}
+
 	if err != nil {
 		return err
 	}
@@ -201,6 +211,10 @@ func (d *decoder) parseSegment() error {
 		return fmt.Errorf("expected number, got %s", string(b))
 	}
 
+	if len(d.currentToken) == 0 {
+		d.currentTokenPos = d.pos
+	}
+
 	d.currentToken = fmt.Sprintf("%s%s", d.currentToken, string(b))
 
 	return nil
@@ -49,6 +49,16 @@ func TestDecode(t *testing.T) {
 			expectedPath:          []uint32{1, 2147483650, 3},
 			expectedStartingPoint: StartingPointMaster,
 		},
+		{
+			path:                  "m/44'",
+			expectedPath:          []uint32{2147483692},
+			expectedStartingPoint: StartingPointMaster,
+		},
+		{
+			path:                  "./1/2'",
+			expectedPath:          []uint32{1, 2147483650},
+			expectedStartingPoint: StartingPointCurrent,
+		},
 		{
 			path: "m/",
 			err:  fmt.Errorf("at position 2, expected number, got EOF"),
@@ -69,6 +79,14 @@ func TestDecode(t *testing.T) {
 			path: "m/2147483648",
 			err:  fmt.Errorf("at position 3, index must be lower than 2^31, got 2147483648"),
 		},
+		{
+			path: "m/2147483648'",
+			err:  fmt.Errorf("at position 3, index must be lower than 2^31, got 2147483648"),
+		},
+		{
+			path: "m/1/2147483648/1",
+			err:  fmt.Errorf("at position 5, index must be lower than 2^31, got 2147483648"),
+		},
 	}
 
 	for i, s := range scenarios {
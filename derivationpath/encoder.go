@@ -7,8 +7,24 @@ import (
 	"strings"
 )
 
+// Encode returns the absolute path string of rawPath, e.g. "m/44'/60'/0'/0/0".
 func Encode(rawPath []uint32) string {
-	segments := []string{string(rune(tokenMaster))}
+	return EncodeWithStartingPoint(StartingPointMaster, rawPath)
+}
+
+// EncodeWithStartingPoint returns the path string of rawPath starting from start,
+// prefixed with "m" for the master key, "." for the current key and ".." for its parent.
+// It's the inverse of Decode.
+func EncodeWithStartingPoint(start StartingPoint, rawPath []uint32) string {
+	prefix := string(rune(tokenMaster))
+	switch start {
+	case StartingPointCurrent:
+		prefix = string(rune(tokenDot))
+	case StartingPointParent:
+		prefix = strings.Repeat(string(rune(tokenDot)), 2)
+	}
+
+	segments := []string{prefix}
 
 	for _, i := range rawPath {
 		suffix := ""
@@ -24,6 +40,17 @@ func Encode(rawPath []uint32) string {
 	return strings.Join(segments, string(rune(tokenSeparator)))
 }
 
+// EncodeToBytes returns the big endian encoding of rawPath sent to the card.
+func EncodeToBytes(rawPath []uint32) []byte {
+	buf := new(bytes.Buffer)
+	for _, segment := range rawPath {
+		// writes to a bytes.Buffer can't fail
+		_ = binary.Write(buf, binary.BigEndian, segment)
+	}
+
+	return buf.Bytes()
+}
+
 func EncodeFromBytes(data []byte) (string, error) {
 	buf := bytes.NewBuffer(data)
 	rawPath := make([]uint32, buf.Len()/4)
@@ -33,3 +33,38 @@ func TestEncode(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeWithStartingPoint(t *testing.T) {
+	scenarios := []struct {
+		start        StartingPoint
+		path         []uint32
+		expectedPath string
+	}{
+		{StartingPointMaster, []uint32{hardenedStart + 44, 0}, "m/44'/0"},
+		{StartingPointCurrent, []uint32{1, 2}, "./1/2"},
+		{StartingPointParent, []uint32{hardenedStart}, "../0'"},
+	}
+
+	for i, s := range scenarios {
+		t.Run(fmt.Sprintf("scenario %d", i), func(t *testing.T) {
+			path := EncodeWithStartingPoint(s.start, s.path)
+			assert.Equal(t, s.expectedPath, path)
+
+			start, rawPath, err := Decode(path)
+			assert.NoError(t, err)
+			assert.Equal(t, s.start, start)
+			assert.Equal(t, s.path, rawPath)
+		})
+	}
+}
+
+func TestEncodeToBytes(t *testing.T) {
+	rawPath := []uint32{hardenedStart + 44, 1}
+	data := EncodeToBytes(rawPath)
+	assert.Equal(t, []byte{0x80, 0x00, 0x00, 0x2C, 0x00, 0x00, 0x00, 0x01}, data)
+	assert.Empty(t, EncodeToBytes(nil))
+
+	path, err := EncodeFromBytes(data)
+	assert.NoError(t, err)
+	assert.Equal(t, Encode(rawPath), path)
+}
@@ -0,0 +1,115 @@
+package keycard
+
+import (
+	"errors"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/globalplatform"
+	"github.com/status-im/keycard-go/identifiers"
+	"github.com/status-im/keycard-go/types"
+)
+
+var ErrNoAppletFound = errors.New("no known applet found")
+var ErrNoCardPublicKey = errors.New("applet has no secure channel public key")
+
+var detectedApplets = []struct {
+	variant types.AppletVariant
+	aid     func() []byte
+}{
+	{types.VariantKeycard, identifiers.DefaultKeycardInstanceAID},
+	{types.VariantCash, func() []byte { return identifiers.CashInstanceAID }},
+	{types.VariantNDEF, func() []byte { return identifiers.NdefInstanceAID }},
+}
+
+// DetectApplet selects the Keycard, Cash and NDEF applets in this order, returning the first one installed
+// with its application info. The NDEF applet info only has Variant and Installed set.
+// The detected applet is left selected.
+func DetectApplet(c types.Channel) (types.AppletVariant, *types.ApplicationInfo, error) {
+	for _, applet := range detectedApplets {
+		cmd := globalplatform.NewCommandSelect(applet.aid())
+		cmd.SetLe(0)
+		resp, err := c.Send(cmd)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if resp.Sw == SwFileNotFound {
+			continue
+		}
+
+		if !resp.IsOK() {
+			return 0, nil, apdu.NewErrBadResponse(resp.Sw, "unexpected response")
+		}
+
+		if applet.variant == types.VariantNDEF {
+			return applet.variant, &types.ApplicationInfo{Variant: types.VariantNDEF, Installed: true}, nil
+		}
+
+		info, err := types.ParseApplicationInfo(resp.Data)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		return info.Variant, info, nil
+	}
+
+	return 0, nil, ErrNoAppletFound
+}
+
+// CardPublicKey selects the Keycard applet with aid, or the default instance if aid is nil,
+// and returns its secure channel public key. It works on pre-initialized and initialized cards,
+// without pairing, so the key can be checked before trusting the card.
+func CardPublicKey(c types.Channel, aid []byte) ([]byte, error) {
+	if aid == nil {
+		aid = identifiers.DefaultKeycardInstanceAID()
+	}
+
+	cmd := globalplatform.NewCommandSelect(aid)
+	cmd.SetLe(0)
+	data, err := sendOK(c, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := types.ParseApplicationInfo(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(info.SecureChannelPublicKey) == 0 {
+		return nil, ErrNoCardPublicKey
+	}
+
+	return info.SecureChannelPublicKey, nil
+}
+
+// Inspect selects the Keycard applet with aid, or the default instance if aid is nil,
+// and returns the report of its application info. It only sends SELECT.
+// If the applet is not installed, the report has Installed false and no error is returned.
+func Inspect(c types.Channel, aid []byte) (*types.CardReport, error) {
+	if aid == nil {
+		aid = identifiers.DefaultKeycardInstanceAID()
+	}
+
+	cmd := globalplatform.NewCommandSelect(aid)
+	cmd.SetLe(0)
+	resp, err := c.Send(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Sw == SwFileNotFound {
+		return &types.CardReport{FreePairingSlots: -1}, nil
+	}
+
+	if !resp.IsOK() {
+		return nil, apdu.NewErrBadResponse(resp.Sw, "unexpected response")
+	}
+
+	info, err := types.ParseApplicationInfo(resp.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.NewCardReport(info), nil
+}
@@ -0,0 +1,117 @@
+package keycard
+
+import (
+	"bytes"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/globalplatform"
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/status-im/keycard-go/identifiers"
+	keycardio "github.com/status-im/keycard-go/io"
+	"github.com/status-im/keycard-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectApplet(t *testing.T) {
+	c := keycardio.NewMockChannel().Respond(applicationInfoResponse(nil), apdu.SwOK)
+	variant, info, err := DetectApplet(c)
+	require.NoError(t, err)
+	assert.Equal(t, types.VariantKeycard, variant)
+	assert.Len(t, info.InstanceUID, 16)
+	assert.Equal(t, identifiers.DefaultKeycardInstanceAID(), c.Sent[0].Data)
+
+	cashInfo := hexutils.HexToBytes("A4 0B 80 01 04 82 02 AA BB 02 02 01 00")
+	c = keycardio.NewMockChannel().
+		Expect(globalplatform.InsSelect, nil, SwFileNotFound).
+		Expect(globalplatform.InsSelect, cashInfo, apdu.SwOK)
+	variant, info, err = DetectApplet(c)
+	require.NoError(t, err)
+	assert.Equal(t, types.VariantCash, variant)
+	assert.Equal(t, []byte{0x01, 0x00}, info.Version)
+	assert.Equal(t, identifiers.CashInstanceAID, c.Sent[1].Data)
+
+	c = newMockChannel(SwFileNotFound, SwFileNotFound, apdu.SwOK)
+	variant, info, err = DetectApplet(c)
+	require.NoError(t, err)
+	assert.Equal(t, types.VariantNDEF, variant)
+	assert.True(t, info.Installed)
+
+	c = newMockChannel(SwFileNotFound, SwFileNotFound, SwFileNotFound)
+	_, _, err = DetectApplet(c)
+	assert.Equal(t, ErrNoAppletFound, err)
+
+	c = newMockChannel(SwConditionsNotSatisfied)
+	_, _, err = DetectApplet(c)
+	assert.Error(t, err)
+	assert.Len(t, c.Sent, 1)
+}
+
+func TestCardPublicKey(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	pubKey := ethcrypto.FromECDSAPub(&cardKey.PublicKey)
+	preInit := append([]byte{types.TagSelectResponsePreInitialized, 0x41}, pubKey...)
+
+	c := keycardio.NewMockChannel().
+		Respond(preInit, apdu.SwOK).
+		Respond(applicationInfoResponse(pubKey), apdu.SwOK).
+		Respond(hexutils.HexToBytes("A4 0B 80 01 04 82 02 AA BB 02 02 01 00"), apdu.SwOK).
+		Respond(nil, SwFileNotFound)
+
+	key, err := CardPublicKey(c, nil)
+	require.NoError(t, err)
+	assert.Equal(t, pubKey, key)
+	assert.Equal(t, identifiers.DefaultKeycardInstanceAID(), c.Sent[0].Data)
+
+	key, err = CardPublicKey(c, nil)
+	require.NoError(t, err)
+	assert.Equal(t, pubKey, key)
+
+	_, err = CardPublicKey(c, identifiers.CashInstanceAID)
+	assert.Equal(t, ErrNoCardPublicKey, err)
+	assert.Equal(t, identifiers.CashInstanceAID, c.Sent[2].Data)
+
+	_, err = CardPublicKey(c, nil)
+	assert.True(t, IsFileNotFound(err))
+}
+
+func TestInspect(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	preInit := append([]byte{types.TagSelectResponsePreInitialized, 0x41}, ethcrypto.FromECDSAPub(&cardKey.PublicKey)...)
+
+	c := keycardio.NewMockChannel().
+		Respond(applicationInfoResponse(nil), apdu.SwOK).
+		Respond(preInit, apdu.SwOK).
+		Respond(nil, SwFileNotFound).
+		Respond(nil, SwConditionsNotSatisfied)
+
+	report, err := Inspect(c, nil)
+	require.NoError(t, err)
+	assert.True(t, report.Installed)
+	assert.True(t, report.Initialized)
+	assert.Equal(t, types.Version{Major: 3, Minor: 1}, report.Version)
+	assert.Equal(t, bytes.Repeat([]byte{0x01}, 16), report.InstanceUID)
+	assert.Equal(t, report.InstanceUID, report.Fingerprint)
+	assert.Equal(t, 5, report.FreePairingSlots)
+	assert.False(t, report.HasKey())
+	assert.Equal(t, types.CapabilityAll, report.Capabilities)
+
+	report, err = Inspect(c, nil)
+	require.NoError(t, err)
+	assert.True(t, report.Installed)
+	assert.False(t, report.Initialized)
+	assert.Equal(t, -1, report.FreePairingSlots)
+	assert.Len(t, report.Fingerprint, 16)
+
+	report, err = Inspect(c, nil)
+	require.NoError(t, err)
+	assert.False(t, report.Installed)
+
+	_, err = Inspect(c, nil)
+	assert.Error(t, err)
+	assert.Equal(t, identifiers.DefaultKeycardInstanceAID(), c.Sent[3].Data)
+}
@@ -0,0 +1,58 @@
+package keycard
+
+import (
+	"errors"
+
+	"github.com/status-im/keycard-go/apdu"
+)
+
+// IsPINError returns the remaining PIN attempts if err is caused by a wrong or blocked PIN.
+func IsPINError(err error) (int, bool) {
+	var wrongPIN *WrongPINError
+	if errors.As(err, &wrongPIN) {
+		return wrongPIN.RemainingAttempts, true
+	}
+
+	if errors.Is(err, ErrPINBlocked) {
+		return 0, true
+	}
+
+	if sw, ok := responseCode(err); ok && sw&0xFFF0 == SwWrongCredentials {
+		return int(sw & 0x000F), true
+	}
+
+	return 0, false
+}
+
+// IsAuthError returns true if err is caused by the card refusing a command because
+// the security status is not satisfied, or by a command needing the secure channel
+// being sent before it's open (ErrSecureChannelNotOpen).
+func IsAuthError(err error) bool {
+	if errors.Is(err, ErrSecureChannelNotOpen) {
+		return true
+	}
+
+	sw, ok := responseCode(err)
+	return ok && sw == SwSecurityConditionNotSatisfied
+}
+
+// IsSecureChannelError returns true if err means the secure channel session is lost
+// and must be opened again with OpenSecureChannel before sending further commands.
+func IsSecureChannelError(err error) bool {
+	return errors.Is(err, ErrInvalidResponseMAC) || errors.Is(err, ErrSecureChannelExhausted) || IsAuthError(err)
+}
+
+// IsFileNotFound returns true if err is caused by the card not finding the selected applet or file.
+func IsFileNotFound(err error) bool {
+	sw, ok := responseCode(err)
+	return ok && sw == SwFileNotFound
+}
+
+func responseCode(err error) (uint16, bool) {
+	var badResponse *apdu.ErrBadResponse
+	if errors.As(err, &badResponse) {
+		return badResponse.Code(), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,46 @@
+package keycard
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPINError(t *testing.T) {
+	remaining, ok := IsPINError(&WrongPINError{RemainingAttempts: 2})
+	assert.True(t, ok)
+	assert.Equal(t, 2, remaining)
+
+	remaining, ok = IsPINError(fmt.Errorf("verify: %w", ErrPINBlocked))
+	assert.True(t, ok)
+	assert.Equal(t, 0, remaining)
+
+	remaining, ok = IsPINError(apdu.NewErrBadResponse(0x63C1, "unexpected response"))
+	assert.True(t, ok)
+	assert.Equal(t, 1, remaining)
+
+	_, ok = IsPINError(errors.New("other"))
+	assert.False(t, ok)
+}
+
+func TestIsAuthError(t *testing.T) {
+	assert.True(t, IsAuthError(apdu.NewErrBadResponse(SwSecurityConditionNotSatisfied, "unexpected response")))
+	assert.False(t, IsAuthError(apdu.NewErrBadResponse(SwFileNotFound, "unexpected response")))
+	assert.False(t, IsAuthError(errors.New("other")))
+	assert.True(t, IsAuthError(ErrSecureChannelNotOpen))
+	assert.True(t, IsAuthError(fmt.Errorf("sign: %w", ErrSecureChannelNotOpen)))
+}
+
+func TestIsFileNotFound(t *testing.T) {
+	assert.True(t, IsFileNotFound(apdu.NewErrBadResponse(SwFileNotFound, "unexpected response")))
+	assert.False(t, IsFileNotFound(apdu.NewErrBadResponse(SwSecurityConditionNotSatisfied, "unexpected response")))
+}
+
+func TestIsSecureChannelError(t *testing.T) {
+	assert.True(t, IsSecureChannelError(ErrInvalidResponseMAC))
+	assert.True(t, IsSecureChannelError(apdu.NewErrBadResponse(SwSecurityConditionNotSatisfied, "unexpected sw in secure channel")))
+	assert.False(t, IsSecureChannelError(ErrPINBlocked))
+}
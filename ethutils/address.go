@@ -0,0 +1,78 @@
+package ethutils
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var ErrInvalidPublicKey = errors.New("public key must be 33 bytes compressed or 65 bytes uncompressed")
+
+// EthereumAddress returns the EIP-55 checksummed address of pubKey, as exported by the card.
+// pubKey can be either compressed or uncompressed.
+func EthereumAddress(pubKey []byte) (string, error) {
+	switch {
+	case len(pubKey) == 65 && pubKey[0] == 0x04:
+		key, err := crypto.UnmarshalPubkey(pubKey)
+		if err != nil {
+			return "", err
+		}
+
+		return crypto.PubkeyToAddress(*key).Hex(), nil
+	case len(pubKey) == 33:
+		key, err := crypto.DecompressPubkey(pubKey)
+		if err != nil {
+			return "", err
+		}
+
+		return crypto.PubkeyToAddress(*key).Hex(), nil
+	default:
+		return "", ErrInvalidPublicKey
+	}
+}
+
+// CompressPublicKey returns the 33 bytes compressed form of the 65 bytes uncompressed pubKey
+// returned by the card. Compressed keys are returned as they are.
+func CompressPublicKey(pubKey []byte) ([]byte, error) {
+	if len(pubKey) == 33 && (pubKey[0] == 0x02 || pubKey[0] == 0x03) {
+		if _, err := crypto.DecompressPubkey(pubKey); err != nil {
+			return nil, err
+		}
+
+		return pubKey, nil
+	}
+
+	if len(pubKey) != 65 || pubKey[0] != 0x04 {
+		return nil, ErrInvalidPublicKey
+	}
+
+	key, err := crypto.UnmarshalPubkey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.CompressPubkey(key), nil
+}
+
+// DecompressPublicKey returns the 65 bytes uncompressed form of the 33 bytes compressed pubKey.
+// Uncompressed keys are returned as they are.
+func DecompressPublicKey(pubKey []byte) ([]byte, error) {
+	if len(pubKey) == 65 && pubKey[0] == 0x04 {
+		if _, err := crypto.UnmarshalPubkey(pubKey); err != nil {
+			return nil, err
+		}
+
+		return pubKey, nil
+	}
+
+	if len(pubKey) != 33 || (pubKey[0] != 0x02 && pubKey[0] != 0x03) {
+		return nil, ErrInvalidPublicKey
+	}
+
+	key, err := crypto.DecompressPubkey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.FromECDSAPub(key), nil
+}
@@ -0,0 +1,68 @@
+package ethutils
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEthereumAddress(t *testing.T) {
+	// private key 1, whose address is well known
+	key, err := crypto.ToECDSA(hexutils.HexToBytes("0000000000000000000000000000000000000000000000000000000000000001"))
+	require.NoError(t, err)
+	expected := "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"
+
+	address, err := EthereumAddress(crypto.FromECDSAPub(&key.PublicKey))
+	require.NoError(t, err)
+	assert.Equal(t, expected, address)
+
+	address, err = EthereumAddress(crypto.CompressPubkey(&key.PublicKey))
+	require.NoError(t, err)
+	assert.Equal(t, expected, address)
+
+	_, err = EthereumAddress(make([]byte, 64))
+	assert.Equal(t, ErrInvalidPublicKey, err)
+
+	invalid := make([]byte, 65)
+	invalid[0] = 0x04
+	_, err = EthereumAddress(invalid)
+	assert.Error(t, err)
+}
+
+func TestCompressPublicKey(t *testing.T) {
+	key, err := crypto.ToECDSA(hexutils.HexToBytes("0000000000000000000000000000000000000000000000000000000000000001"))
+	require.NoError(t, err)
+	uncompressed := crypto.FromECDSAPub(&key.PublicKey)
+	compressed := hexutils.HexToBytes("0279BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798")
+
+	pub, err := CompressPublicKey(uncompressed)
+	require.NoError(t, err)
+	assert.Equal(t, compressed, pub)
+
+	pub, err = CompressPublicKey(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, compressed, pub)
+
+	pub, err = DecompressPublicKey(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, uncompressed, pub)
+
+	pub, err = DecompressPublicKey(uncompressed)
+	require.NoError(t, err)
+	assert.Equal(t, uncompressed, pub)
+
+	_, err = CompressPublicKey(uncompressed[1:])
+	assert.Equal(t, ErrInvalidPublicKey, err)
+
+	wrongPrefix := append([]byte{0x05}, compressed[1:]...)
+	_, err = DecompressPublicKey(wrongPrefix)
+	assert.Equal(t, ErrInvalidPublicKey, err)
+
+	offCurve := append([]byte{}, uncompressed...)
+	offCurve[64] ^= 0x01
+	_, err = CompressPublicKey(offCurve)
+	assert.Error(t, err)
+}
@@ -19,6 +19,7 @@ const (
 	InsLoad                 = 0xE8
 	InsInstall              = 0xE6
 	InsGetStatus            = 0xF2
+	InsReadBinary           = 0xB0
 
 	P1ExternalAuthenticateCMAC         = 0x01
 	P1InstallForLoad                   = 0x02
@@ -31,6 +32,9 @@ const (
 	P1GetStatusExecLoadFiles           = 0x20
 	P1GetStatusExecLoadFilesAndModules = 0x10
 
+	P1SelectByFileID = 0x00
+	P2SelectNoFCI    = 0x0C
+
 	P2GetStatusTLVData             = 0x02
 	P2DeleteObject                 = 0x00
 	P2DeleteObjectAndRelatedObject = 0x80
@@ -61,6 +65,34 @@ func NewCommandSelect(aid []byte) *apdu.Command {
 	return c
 }
 
+// NewCommandSelectFile returns a Select command selecting the elementary file fid of the current application,
+// as defined in the iso7816 specifications.
+func NewCommandSelectFile(fid uint16) *apdu.Command {
+	return apdu.NewCommand(
+		ClaISO7816,
+		InsSelect,
+		P1SelectByFileID,
+		P2SelectNoFCI,
+		[]byte{byte(fid >> 8), byte(fid)},
+	)
+}
+
+// NewCommandReadBinary returns a Read Binary command reading length bytes at offset of the selected file,
+// as defined in the iso7816 specifications.
+func NewCommandReadBinary(offset uint16, length uint8) *apdu.Command {
+	c := apdu.NewCommand(
+		ClaISO7816,
+		InsReadBinary,
+		uint8(offset>>8),
+		uint8(offset),
+		nil,
+	)
+
+	c.SetLe(length)
+
+	return c
+}
+
 // NewCommandInitializeUpdate returns an Initialize Update command as defined in the globalplatform specifications.
 func NewCommandInitializeUpdate(challenge []byte) *apdu.Command {
 	c := apdu.NewCommand(
@@ -3,6 +3,7 @@ package globalplatform
 import (
 	"testing"
 
+	"github.com/status-im/keycard-go/apdu"
 	"github.com/status-im/keycard-go/hexutils"
 	"github.com/stretchr/testify/assert"
 )
@@ -15,6 +16,32 @@ func TestNewCommandSelect(t *testing.T) {
 	assert.Equal(t, uint8(0xA4), cmd.Ins)
 	assert.Equal(t, uint8(0x04), cmd.P1)
 	assert.Equal(t, uint8(0x00), cmd.P2)
+
+	_, err := NewCommandSelect(make([]byte, 256)).Serialize()
+	assert.ErrorIs(t, err, apdu.ErrDataTooLong)
+}
+
+func TestNewCommandSelectFile(t *testing.T) {
+	cmd := NewCommandSelectFile(0xE103)
+
+	assert.Equal(t, uint8(0x00), cmd.Cla)
+	assert.Equal(t, uint8(0xA4), cmd.Ins)
+	assert.Equal(t, uint8(0x00), cmd.P1)
+	assert.Equal(t, uint8(0x0C), cmd.P2)
+	assert.Equal(t, []byte{0xE1, 0x03}, cmd.Data)
+}
+
+func TestNewCommandReadBinary(t *testing.T) {
+	cmd := NewCommandReadBinary(0x0102, 0x0F)
+
+	assert.Equal(t, uint8(0x00), cmd.Cla)
+	assert.Equal(t, uint8(0xB0), cmd.Ins)
+	assert.Equal(t, uint8(0x01), cmd.P1)
+	assert.Equal(t, uint8(0x02), cmd.P2)
+
+	raw, err := cmd.Serialize()
+	assert.NoError(t, err)
+	assert.Equal(t, "00B001020F", hexutils.BytesToHex(raw))
 }
 
 func TestNewCommandInitializeUpdate(t *testing.T) {
@@ -6,6 +6,9 @@ var (
 	GlobalPlatformDefaultKey = []byte{0x40, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e, 0x4f}
 	KeycardDevelopmentKey    = []byte{0xc2, 0x12, 0xe0, 0x73, 0xff, 0x8b, 0x4b, 0xbf, 0xaf, 0xf4, 0xde, 0x8a, 0xb6, 0x55, 0x22, 0x1f}
 
+	// IssuerSecurityDomainAID is the GlobalPlatform default ISD, used by JCOP cards.
+	IssuerSecurityDomainAID = []byte{0xA0, 0x00, 0x00, 0x01, 0x51, 0x00, 0x00, 0x00}
+
 	PackageAID = []byte{0xA0, 0x00, 0x00, 0x08, 0x04, 0x00, 0x01}
 
 	KeycardAID = []byte{0xA0, 0x00, 0x00, 0x08, 0x04, 0x00, 0x01, 0x01}
@@ -26,5 +29,14 @@ func KeycardInstanceAID(index int) ([]byte, error) {
 		return nil, ErrInvalidInstanceIndex
 	}
 
-	return append(KeycardAID, byte(index)), nil
+	aid := make([]byte, 0, len(KeycardAID)+1)
+	aid = append(aid, KeycardAID...)
+
+	return append(aid, byte(index)), nil
+}
+
+// DefaultKeycardInstanceAID returns the AID of the Keycard applet instance installed by default.
+func DefaultKeycardInstanceAID() []byte {
+	aid, _ := KeycardInstanceAID(KeycardDefaultInstanceIndex)
+	return aid
 }
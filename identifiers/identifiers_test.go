@@ -0,0 +1,24 @@
+package identifiers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeycardInstanceAID(t *testing.T) {
+	aid, err := KeycardInstanceAID(2)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xA0, 0x00, 0x00, 0x08, 0x04, 0x00, 0x01, 0x01, 0x02}, aid)
+
+	aid[0] = 0x00
+	assert.Equal(t, uint8(0xA0), KeycardAID[0])
+
+	_, err = KeycardInstanceAID(0)
+	assert.Equal(t, ErrInvalidInstanceIndex, err)
+	_, err = KeycardInstanceAID(256)
+	assert.Equal(t, ErrInvalidInstanceIndex, err)
+
+	assert.Equal(t, []byte{0xA0, 0x00, 0x00, 0x08, 0x04, 0x00, 0x01, 0x01, 0x01}, DefaultKeycardInstanceAID())
+}
@@ -0,0 +1,38 @@
+package io
+
+import (
+	"errors"
+
+	"github.com/status-im/keycard-go/apdu"
+)
+
+// ErrCommandCaptured is returned by CaptureChannel for every command, instead of a response.
+var ErrCommandCaptured = errors.New("command captured, not sent")
+
+// CaptureChannel records commands without sending them, to preview what an action would send.
+// Every Send fails with ErrCommandCaptured, so actions stop after their first command.
+// With a CommandSet that has no open secure channel and no application info, commands
+// are captured before encryption, as built by the NewCommand functions.
+type CaptureChannel struct {
+	Captured []*apdu.Command
+}
+
+// NewCaptureChannel returns a new CaptureChannel.
+func NewCaptureChannel() *CaptureChannel {
+	return &CaptureChannel{}
+}
+
+// Send records cmd and returns ErrCommandCaptured.
+func (c *CaptureChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	c.Captured = append(c.Captured, cmd)
+	return nil, ErrCommandCaptured
+}
+
+// Last returns the last captured command, or nil if none was captured.
+func (c *CaptureChannel) Last() *apdu.Command {
+	if len(c.Captured) == 0 {
+		return nil
+	}
+
+	return c.Captured[len(c.Captured)-1]
+}
@@ -0,0 +1,20 @@
+package io
+
+import (
+	"testing"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureChannel(t *testing.T) {
+	c := NewCaptureChannel()
+	assert.Nil(t, c.Last())
+
+	cmd := apdu.NewCommand(0x80, 0xC0, 0x00, 0x00, []byte{0x01})
+	resp, err := c.Send(cmd)
+	assert.Equal(t, ErrCommandCaptured, err)
+	assert.Nil(t, resp)
+	assert.Equal(t, cmd, c.Last())
+	assert.Len(t, c.Captured, 1)
+}
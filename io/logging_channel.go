@@ -0,0 +1,56 @@
+package io
+
+import (
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/status-im/keycard-go/types"
+)
+
+// LoggingChannel wraps a channel logging the header of every command and the status word of every response.
+// Data is only logged if LogData is true, since above a secure channel it contains PINs and keys.
+type LoggingChannel struct {
+	c      types.Channel
+	logger log.Logger
+
+	// LogData enables hex dumps of commands and responses data at trace level.
+	LogData bool
+}
+
+// NewLoggingChannel returns a new LoggingChannel sending commands through c and logging to l.
+// If l is nil the package logger is used.
+func NewLoggingChannel(c types.Channel, l log.Logger) *LoggingChannel {
+	if l == nil {
+		l = logger
+	}
+
+	return &LoggingChannel{
+		c:      c,
+		logger: l,
+	}
+}
+
+// Send sends cmd logging it and its response.
+func (c *LoggingChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	c.logger.Debug("send apdu", "cla", hexByte(cmd.Cla), "ins", hexByte(cmd.Ins), "p1", hexByte(cmd.P1), "p2", hexByte(cmd.P2), "lc", len(cmd.Data))
+	if c.LogData && len(cmd.Data) > 0 {
+		c.logger.Trace("apdu command data", "hex", hexutils.BytesToHexWithSpaces(cmd.Data))
+	}
+
+	resp, err := c.c.Send(cmd)
+	if err != nil {
+		c.logger.Debug("apdu failed", "ins", hexByte(cmd.Ins), "err", err)
+		return nil, err
+	}
+
+	c.logger.Debug("received apdu", "ins", hexByte(cmd.Ins), "sw", hexutils.BytesToHex([]byte{resp.Sw1, resp.Sw2}), "len", len(resp.Data))
+	if c.LogData && len(resp.Data) > 0 {
+		c.logger.Trace("apdu response data", "hex", hexutils.BytesToHexWithSpaces(resp.Data))
+	}
+
+	return resp, nil
+}
+
+func hexByte(b uint8) string {
+	return hexutils.BytesToHex([]byte{b})
+}
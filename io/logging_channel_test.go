@@ -0,0 +1,48 @@
+package io
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedLog struct {
+	level log.Lvl
+	msg   string
+	ctx   []interface{}
+}
+
+func newRecordingLogger(records *[]recordedLog) log.Logger {
+	l := log.New()
+	l.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		*records = append(*records, recordedLog{r.Lvl, r.Msg, r.Ctx})
+		return nil
+	}))
+
+	return l
+}
+
+func TestLoggingChannel_Send(t *testing.T) {
+	records := []recordedLog{}
+	c := NewMockChannel().Respond([]byte{0xAA}, apdu.SwOK).Respond([]byte{0xBB}, apdu.SwOK)
+	lc := NewLoggingChannel(c, newRecordingLogger(&records))
+
+	_, err := lc.Send(apdu.NewCommand(0x80, 0x20, 0x00, 0x00, []byte("123456")))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "send apdu", records[0].msg)
+	assert.Equal(t, []interface{}{"cla", "80", "ins", "20", "p1", "00", "p2", "00", "lc", 6}, records[0].ctx)
+	assert.Equal(t, []interface{}{"ins", "20", "sw", "9000", "len", 1}, records[1].ctx)
+
+	records = records[:0]
+	lc.LogData = true
+	_, err = lc.Send(apdu.NewCommand(0x80, 0x20, 0x00, 0x00, []byte{0x01}))
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, log.LvlTrace, records[1].level)
+	assert.Equal(t, []interface{}{"hex", "01"}, records[1].ctx)
+	assert.Equal(t, []interface{}{"hex", "BB"}, records[3].ctx)
+}
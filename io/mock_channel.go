@@ -0,0 +1,92 @@
+package io
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/status-im/keycard-go/apdu"
+)
+
+// ErrNoMockResponse is returned by MockChannel when a command is sent and no response is scripted.
+var ErrNoMockResponse = errors.New("no scripted response")
+
+// ErrUnexpectedCommand is returned by MockChannel when a command doesn't match the expected one.
+type ErrUnexpectedCommand struct {
+	ExpectedIns uint8
+	Ins         uint8
+}
+
+// Error implements the error interface.
+func (e *ErrUnexpectedCommand) Error() string {
+	return fmt.Sprintf("unexpected command %02X, expected %02X", e.Ins, e.ExpectedIns)
+}
+
+type mockStep struct {
+	checkIns bool
+	ins      uint8
+	resp     *apdu.Response
+	err      error
+}
+
+// MockChannel implements a channel returning scripted responses, to test code using commands without a card.
+// Responses are returned in the order they are added and every sent command is recorded in Sent.
+type MockChannel struct {
+	Sent  []*apdu.Command
+	steps []mockStep
+}
+
+// NewMockChannel returns a new MockChannel without scripted responses.
+func NewMockChannel() *MockChannel {
+	return &MockChannel{}
+}
+
+// Respond adds a response with data and sw returned to the next command, whatever it is.
+func (c *MockChannel) Respond(data []byte, sw uint16) *MockChannel {
+	c.steps = append(c.steps, mockStep{resp: newMockResponse(data, sw)})
+	return c
+}
+
+// Expect adds a response with data and sw returned to the next command if its instruction is ins.
+// Otherwise Send returns an ErrUnexpectedCommand.
+func (c *MockChannel) Expect(ins uint8, data []byte, sw uint16) *MockChannel {
+	c.steps = append(c.steps, mockStep{checkIns: true, ins: ins, resp: newMockResponse(data, sw)})
+	return c
+}
+
+// Fail makes the next command fail with err, as a transport error would.
+func (c *MockChannel) Fail(err error) *MockChannel {
+	c.steps = append(c.steps, mockStep{err: err})
+	return c
+}
+
+// Pending returns the number of scripted responses not returned yet.
+func (c *MockChannel) Pending() int {
+	return len(c.steps)
+}
+
+// Send records cmd and returns the next scripted response.
+func (c *MockChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	c.Sent = append(c.Sent, cmd)
+
+	if len(c.steps) == 0 {
+		return nil, ErrNoMockResponse
+	}
+
+	step := c.steps[0]
+	c.steps = c.steps[1:]
+
+	if step.checkIns && step.ins != cmd.Ins {
+		return nil, &ErrUnexpectedCommand{ExpectedIns: step.ins, Ins: cmd.Ins}
+	}
+
+	return step.resp, step.err
+}
+
+func newMockResponse(data []byte, sw uint16) *apdu.Response {
+	return &apdu.Response{
+		Data: data,
+		Sw1:  uint8(sw >> 8),
+		Sw2:  uint8(sw),
+		Sw:   sw,
+	}
+}
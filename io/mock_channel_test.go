@@ -0,0 +1,44 @@
+package io
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockChannel(t *testing.T) {
+	transportErr := errors.New("reader removed")
+	c := NewMockChannel().
+		Respond([]byte{0x01}, apdu.SwOK).
+		Expect(0x20, nil, 0x63C2).
+		Expect(0x20, nil, apdu.SwOK).
+		Fail(transportErr)
+
+	cmd := apdu.NewCommand(0x80, 0x10, 0, 0, nil)
+	resp, err := c.Send(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, resp.Data)
+	assert.True(t, resp.IsOK())
+
+	resp, err = c.Send(apdu.NewCommand(0x80, 0x20, 0, 0, nil))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0x63C2), resp.Sw)
+	assert.Equal(t, uint8(0x63), resp.Sw1)
+	assert.Equal(t, uint8(0xC2), resp.Sw2)
+
+	_, err = c.Send(cmd)
+	assert.Equal(t, &ErrUnexpectedCommand{ExpectedIns: 0x20, Ins: 0x10}, err)
+
+	_, err = c.Send(cmd)
+	assert.Equal(t, transportErr, err)
+
+	_, err = c.Send(cmd)
+	assert.Equal(t, ErrNoMockResponse, err)
+
+	assert.Len(t, c.Sent, 5)
+	assert.Equal(t, cmd, c.Sent[0])
+	assert.Equal(t, 0, c.Pending())
+}
@@ -1,6 +1,8 @@
 package io
 
 import (
+	"errors"
+
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/status-im/keycard-go/apdu"
 	"github.com/status-im/keycard-go/globalplatform"
@@ -9,6 +11,14 @@ import (
 
 var logger = log.New("package", "keycard-go/io")
 
+// MaxGetResponses is the number of GET RESPONSE commands NormalChannel sends for one command,
+// enough for 64KB of response data.
+const MaxGetResponses = 256
+
+// ErrTooManyGetResponses is returned by NormalChannel when the card still reports more data
+// available after MaxGetResponses GET RESPONSE commands.
+var ErrTooManyGetResponses = errors.New("too many get response commands")
+
 // Transmitter defines an interface with one method to transmit raw commands and receive raw responses.
 type Transmitter interface {
 	Transmit([]byte) ([]byte, error)
@@ -25,30 +35,50 @@ func NewNormalChannel(t Transmitter) *NormalChannel {
 }
 
 // Send sends apdu commands to the current Transmitter.
-// Based on the smartcard transport protocol (T=0, T=1), it checks responses and sends a Get Response
-// command in case of T=0.
+// Based on the smartcard transport protocol (T=0, T=1), it checks responses and sends Get Response
+// commands while the card reports more data available, returning the concatenated data.
+// It fails with ErrTooManyGetResponses after MaxGetResponses of them, so a faulty card
+// can't keep it looping.
 func (c *NormalChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
-	rawCmd, err := cmd.Serialize()
+	resp, err := c.transmit(cmd)
 	if err != nil {
 		return nil, err
 	}
 
-	logger.Debug("apdu command", "hex", hexutils.BytesToHexWithSpaces(rawCmd))
-	rawResp, err := c.t.Transmit(rawCmd)
-	if err != nil {
-		return nil, err
+	var data []byte
+	for i := 0; resp.Sw1 == globalplatform.Sw1ResponseDataIncomplete; i++ {
+		if i == MaxGetResponses {
+			return nil, ErrTooManyGetResponses
+		}
+
+		data = append(data, resp.Data...)
+
+		getResponse := globalplatform.NewCommandGetResponse(resp.Sw2)
+		resp, err = c.transmit(getResponse)
+		if err != nil {
+			return nil, err
+		}
 	}
-	logger.Debug("apdu response", "hex", hexutils.BytesToHexWithSpaces(rawResp))
 
-	resp, err := apdu.ParseResponse(rawResp)
+	if len(data) > 0 {
+		resp.Data = append(data, resp.Data...)
+	}
+
+	return resp, nil
+}
+
+func (c *NormalChannel) transmit(cmd *apdu.Command) (*apdu.Response, error) {
+	rawCmd, err := cmd.Serialize()
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.Sw1 == globalplatform.Sw1ResponseDataIncomplete && (cmd.Cla != globalplatform.ClaISO7816 || cmd.Ins != globalplatform.InsGetResponse) {
-		getResponse := globalplatform.NewCommandGetResponse(resp.Sw2)
-		return c.Send(getResponse)
+	logger.Debug("apdu command", "hex", hexutils.BytesToHexWithSpaces(rawCmd))
+	rawResp, err := c.t.Transmit(rawCmd)
+	if err != nil {
+		return nil, err
 	}
+	logger.Debug("apdu response", "hex", hexutils.BytesToHexWithSpaces(rawResp))
 
 	return apdu.ParseResponse(rawResp)
 }
@@ -0,0 +1,67 @@
+package io
+
+import (
+	"testing"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTransmitter struct {
+	sent      [][]byte
+	responses [][]byte
+}
+
+func (t *fakeTransmitter) Transmit(raw []byte) ([]byte, error) {
+	t.sent = append(t.sent, raw)
+	resp := t.responses[0]
+	t.responses = t.responses[1:]
+	return resp, nil
+}
+
+func TestNormalChannel_Send(t *testing.T) {
+	tr := &fakeTransmitter{
+		responses: [][]byte{hexutils.HexToBytes("0102 9000")},
+	}
+
+	c := NewNormalChannel(tr)
+	resp, err := c.Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(apdu.SwOK), resp.Sw)
+	assert.Equal(t, hexutils.HexToBytes("0102"), resp.Data)
+	assert.Len(t, tr.sent, 1)
+}
+
+func TestNormalChannel_Send_GetResponse(t *testing.T) {
+	tr := &fakeTransmitter{
+		responses: [][]byte{
+			hexutils.HexToBytes("0102 6102"),
+			hexutils.HexToBytes("0304 6101"),
+			hexutils.HexToBytes("05 9000"),
+		},
+	}
+
+	c := NewNormalChannel(tr)
+	resp, err := c.Send(apdu.NewCommand(0x80, 0xC2, 0, 0, nil))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(apdu.SwOK), resp.Sw)
+	assert.Equal(t, hexutils.HexToBytes("0102030405"), resp.Data)
+
+	require.Len(t, tr.sent, 3)
+	assert.Equal(t, "00 C0 00 00 02", hexutils.BytesToHexWithSpaces(tr.sent[1]))
+	assert.Equal(t, "00 C0 00 00 01", hexutils.BytesToHexWithSpaces(tr.sent[2]))
+}
+
+func TestNormalChannel_Send_TooManyGetResponses(t *testing.T) {
+	tr := &fakeTransmitter{}
+	for i := 0; i <= MaxGetResponses; i++ {
+		tr.responses = append(tr.responses, hexutils.HexToBytes("01 6101"))
+	}
+
+	c := NewNormalChannel(tr)
+	_, err := c.Send(apdu.NewCommand(0x80, 0xC2, 0, 0, nil))
+	assert.Equal(t, ErrTooManyGetResponses, err)
+	assert.Len(t, tr.sent, MaxGetResponses+1)
+}
@@ -0,0 +1,7 @@
+// Package pcsc implements a types.Channel talking to cards inserted in PC/SC readers.
+//
+// The package wraps github.com/ebfe/scard, which links against the system PC/SC
+// library (libpcsclite on linux), so it is only built with the pcsc build tag:
+//
+//	go build -tags pcsc
+package pcsc
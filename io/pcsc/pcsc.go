@@ -0,0 +1,89 @@
+//go:build pcsc
+// +build pcsc
+
+package pcsc
+
+import (
+	"errors"
+
+	"github.com/ebfe/scard"
+	keycardio "github.com/status-im/keycard-go/io"
+)
+
+// ErrNoReaders is returned when no PC/SC readers are connected.
+var ErrNoReaders = errors.New("no smartcard readers found")
+
+// Card is a connection to a card inserted in a PC/SC reader.
+// It sends commands through a keycardio.NormalChannel and must be closed after use.
+type Card struct {
+	*keycardio.NormalChannel
+	ctx  *scard.Context
+	card *scard.Card
+}
+
+// Readers returns the names of the PC/SC readers connected to the system.
+func Readers() ([]string, error) {
+	ctx, err := scard.EstablishContext()
+	if err != nil {
+		return nil, err
+	}
+
+	defer ctx.Release()
+
+	readers, err := ctx.ListReaders()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(readers) == 0 {
+		return nil, ErrNoReaders
+	}
+
+	return readers, nil
+}
+
+// Connect connects to the card inserted in the named reader.
+// If reader is empty, the first available reader is used.
+func Connect(reader string) (*Card, error) {
+	ctx, err := scard.EstablishContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if reader == "" {
+		readers, err := ctx.ListReaders()
+		if err != nil {
+			ctx.Release()
+			return nil, err
+		}
+
+		if len(readers) == 0 {
+			ctx.Release()
+			return nil, ErrNoReaders
+		}
+
+		reader = readers[0]
+	}
+
+	card, err := ctx.Connect(reader, scard.ShareShared, scard.ProtocolAny)
+	if err != nil {
+		ctx.Release()
+		return nil, err
+	}
+
+	return &Card{
+		NormalChannel: keycardio.NewNormalChannel(card),
+		ctx:           ctx,
+		card:          card,
+	}, nil
+}
+
+// Close disconnects from the card, leaving it powered in the reader, and releases the PC/SC context.
+func (c *Card) Close() error {
+	err := c.card.Disconnect(scard.LeaveCard)
+	if releaseErr := c.ctx.Release(); err == nil {
+		err = releaseErr
+	}
+
+	return err
+}
@@ -0,0 +1,174 @@
+package io
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/types"
+)
+
+// Exchange is a command and the response of the card, both hex encoded, as saved in recordings.
+type Exchange struct {
+	Command  string `json:"command"`
+	Response string `json:"response"`
+}
+
+// ErrReplayMismatch is returned by ReplayChannel when a command differs from the recorded one.
+type ErrReplayMismatch struct {
+	Index    int
+	Expected string
+	Command  string
+}
+
+// Error implements the error interface.
+func (e *ErrReplayMismatch) Error() string {
+	if e.Expected == "" {
+		return fmt.Sprintf("command %d %s sent after the end of the recording", e.Index, e.Command)
+	}
+
+	return fmt.Sprintf("command %d is %s, recorded %s", e.Index, e.Command, e.Expected)
+}
+
+// RecordingChannel wraps a channel recording every command and response, to replay a session
+// with ReplayChannel. Commands are recorded as sent to the wrapped channel, so recordings of plain
+// commands should be handled as carefully as the data they contain.
+type RecordingChannel struct {
+	c         types.Channel
+	Exchanges []Exchange
+}
+
+// NewRecordingChannel returns a new RecordingChannel sending commands through c.
+func NewRecordingChannel(c types.Channel) *RecordingChannel {
+	return &RecordingChannel{c: c}
+}
+
+// Send sends cmd through the wrapped channel and records it with its response.
+// Commands failing with a transport error are not recorded.
+func (c *RecordingChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	rawCmd, err := cmd.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.Send(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Exchanges = append(c.Exchanges, Exchange{
+		Command:  hex.EncodeToString(rawCmd),
+		Response: hex.EncodeToString(serializeResponse(resp)),
+	})
+
+	return resp, nil
+}
+
+// Save writes the recorded exchanges to w as JSON.
+func (c *RecordingChannel) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(c.Exchanges)
+}
+
+// SaveFile writes the recorded exchanges to the JSON file at path.
+func (c *RecordingChannel) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err = c.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// ReplayChannel answers commands with the responses of a recording, failing with ErrReplayMismatch
+// as soon as a command differs from the recorded one.
+// Sessions using a secure channel can only be replayed if the client key and challenges are the
+// same as when recording, for example by setting the same fixed source with CommandSet.SetRandom.
+// INIT can't be replayed: the IV of its encrypted data is always random.
+type ReplayChannel struct {
+	exchanges []Exchange
+	pos       int
+}
+
+// NewReplayChannel returns a ReplayChannel replaying exchanges.
+func NewReplayChannel(exchanges []Exchange) *ReplayChannel {
+	return &ReplayChannel{exchanges: exchanges}
+}
+
+// LoadReplayChannel returns a ReplayChannel replaying the JSON recording read from r.
+func LoadReplayChannel(r io.Reader) (*ReplayChannel, error) {
+	var exchanges []Exchange
+	if err := json.NewDecoder(r).Decode(&exchanges); err != nil {
+		return nil, err
+	}
+
+	return NewReplayChannel(exchanges), nil
+}
+
+// LoadReplayFile returns a ReplayChannel replaying the JSON recording file at path.
+func LoadReplayFile(path string) (*ReplayChannel, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return LoadReplayChannel(f)
+}
+
+// Pending returns the number of recorded exchanges not replayed yet.
+func (c *ReplayChannel) Pending() int {
+	return len(c.exchanges) - c.pos
+}
+
+// Send checks that cmd is the next recorded command and returns its recorded response.
+func (c *ReplayChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	rawCmd, err := cmd.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	mismatch := &ErrReplayMismatch{Index: c.pos, Command: hex.EncodeToString(rawCmd)}
+	if c.pos >= len(c.exchanges) {
+		return nil, mismatch
+	}
+
+	exchange := c.exchanges[c.pos]
+	expected, err := hex.DecodeString(exchange.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(expected, rawCmd) {
+		mismatch.Expected = exchange.Command
+		return nil, mismatch
+	}
+
+	rawResp, err := hex.DecodeString(exchange.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	c.pos++
+
+	return apdu.ParseResponse(rawResp)
+}
+
+func serializeResponse(resp *apdu.Response) []byte {
+	raw := make([]byte, 0, len(resp.Data)+2)
+	raw = append(raw, resp.Data...)
+
+	return append(raw, resp.Sw1, resp.Sw2)
+}
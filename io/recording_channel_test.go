@@ -0,0 +1,62 @@
+package io
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingChannel_Replay(t *testing.T) {
+	card := NewMockChannel().
+		Respond([]byte{0x01, 0x02}, apdu.SwOK).
+		Respond(nil, 0x6A82)
+	rc := NewRecordingChannel(card)
+
+	_, err := rc.Send(apdu.NewCommand(0x00, 0xA4, 0x04, 0x00, []byte{0xA0, 0x00}))
+	require.NoError(t, err)
+	_, err = rc.Send(apdu.NewCommand(0x80, 0xCA, 0x00, 0x00, nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Exchange{
+		{Command: "00a4040002a000", Response: "01029000"},
+		{Command: "80ca0000", Response: "6a82"},
+	}, rc.Exchanges)
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	require.NoError(t, rc.SaveFile(path))
+
+	replay, err := LoadReplayFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, replay.Pending())
+
+	resp, err := replay.Send(apdu.NewCommand(0x00, 0xA4, 0x04, 0x00, []byte{0xA0, 0x00}))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x02}, resp.Data)
+	assert.Equal(t, uint16(apdu.SwOK), resp.Sw)
+
+	resp, err = replay.Send(apdu.NewCommand(0x80, 0xCA, 0x00, 0x00, nil))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0x6A82), resp.Sw)
+	assert.Equal(t, 0, replay.Pending())
+
+	_, err = replay.Send(apdu.NewCommand(0x80, 0xCA, 0x00, 0x00, nil))
+	assert.Equal(t, &ErrReplayMismatch{Index: 2, Command: "80ca0000"}, err)
+}
+
+func TestReplayChannel_Mismatch(t *testing.T) {
+	replay, err := LoadReplayChannel(bytes.NewBufferString(`[{"command": "80ca0000", "response": "9000"}]`))
+	require.NoError(t, err)
+
+	_, err = replay.Send(apdu.NewCommand(0x80, 0xCA, 0x01, 0x00, nil))
+	assert.Equal(t, &ErrReplayMismatch{Index: 0, Expected: "80ca0000", Command: "80ca0100"}, err)
+	assert.Equal(t, 1, replay.Pending())
+
+	rc := NewRecordingChannel(NewMockChannel().Fail(ErrNoMockResponse))
+	_, err = rc.Send(apdu.NewCommand(0x80, 0xCA, 0x00, 0x00, nil))
+	assert.Equal(t, ErrNoMockResponse, err)
+	assert.Empty(t, rc.Exchanges)
+}
@@ -0,0 +1,201 @@
+// Package relay sends APDUs over a network connection, so that a client can use a card
+// attached to a reader on another machine.
+//
+// Every message is a frame made of a 1 byte type, a 2 bytes big endian length and the payload.
+// The client starts with a hello frame carrying the protocol magic and version, which the server
+// echoes back, then sends command frames each answered by a response or an error frame.
+package relay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	keycardio "github.com/status-im/keycard-go/io"
+)
+
+const (
+	frameHello    byte = 0x00
+	frameCommand  byte = 0x01
+	frameResponse byte = 0x02
+	frameError    byte = 0x03
+
+	// MaxFrameLength is the longest frame payload, enough for an extended length APDU.
+	MaxFrameLength = 0xFFFF
+)
+
+// Version is the version of the relay protocol sent in the handshake.
+const Version byte = 0x01
+
+var magic = []byte("KCRL")
+
+var (
+	ErrHandshakeFailed    = errors.New("relay handshake failed")
+	ErrUnexpectedFrame    = errors.New("unexpected relay frame")
+	ErrFrameTooLong       = fmt.Errorf("relay frame cannot be longer than %d bytes", MaxFrameLength)
+	errUnsupportedVersion = errors.New("unsupported relay protocol version")
+)
+
+// RemoteError is returned by the client when the server failed to transmit a command to the card.
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return "relay server: " + e.Message
+}
+
+// Client sends commands to a relay server. It embeds a keycardio.NormalChannel,
+// so it can be used as the channel of a CommandSet.
+type Client struct {
+	*keycardio.NormalChannel
+	conn net.Conn
+}
+
+// Dial connects to the relay server at address and performs the handshake.
+func Dial(network, address string) (*Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// NewClient performs the handshake on conn and returns a Client sending commands through it.
+func NewClient(conn net.Conn) (*Client, error) {
+	hello := append(append([]byte{}, magic...), Version)
+	if err := writeFrame(conn, frameHello, hello); err != nil {
+		return nil, err
+	}
+
+	typ, payload, err := readFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ != frameHello || !bytes.Equal(payload, hello) {
+		return nil, ErrHandshakeFailed
+	}
+
+	c := &Client{conn: conn}
+	c.NormalChannel = keycardio.NewNormalChannel(keycardio.TransmitterFunc(c.transmit))
+
+	return c, nil
+}
+
+func (c *Client) transmit(raw []byte) ([]byte, error) {
+	if err := writeFrame(c.conn, frameCommand, raw); err != nil {
+		return nil, err
+	}
+
+	typ, payload, err := readFrame(c.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case frameResponse:
+		return payload, nil
+	case frameError:
+		return nil, &RemoteError{Message: string(payload)}
+	default:
+		return nil, ErrUnexpectedFrame
+	}
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Serve answers the handshake of the client connected to conn and transmits its commands to t
+// until the client disconnects. Transmission errors are sent back to the client, which can retry.
+// It returns nil when the client closes the connection.
+func Serve(conn net.Conn, t keycardio.Transmitter) error {
+	typ, payload, err := readFrame(conn)
+	if err != nil {
+		return err
+	}
+
+	if typ != frameHello || len(payload) != len(magic)+1 || !bytes.Equal(payload[:len(magic)], magic) {
+		return ErrHandshakeFailed
+	}
+
+	if payload[len(magic)] != Version {
+		writeFrame(conn, frameError, []byte(errUnsupportedVersion.Error()))
+		return errUnsupportedVersion
+	}
+
+	if err = writeFrame(conn, frameHello, payload); err != nil {
+		return err
+	}
+
+	for {
+		typ, payload, err = readFrame(conn)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if typ != frameCommand {
+			return ErrUnexpectedFrame
+		}
+
+		resp, err := t.Transmit(payload)
+		if err != nil {
+			err = writeFrame(conn, frameError, []byte(err.Error()))
+		} else {
+			err = writeFrame(conn, frameResponse, resp)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeFrame(w io.Writer, typ byte, payload []byte) error {
+	if len(payload) > MaxFrameLength {
+		return ErrFrameTooLong
+	}
+
+	frame := make([]byte, 3, 3+len(payload))
+	frame[0] = typ
+	binary.BigEndian.PutUint16(frame[1:], uint16(len(payload)))
+	frame = append(frame, payload...)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame returns io.EOF only if the connection was closed between two frames.
+func readFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 3)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[1:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return 0, nil, err
+	}
+
+	return header[0], payload, nil
+}
@@ -0,0 +1,83 @@
+package relay
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/status-im/keycard-go/apdu"
+	keycardio "github.com/status-im/keycard-go/io"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t keycardio.Transmitter) (net.Conn, chan error) {
+	clientConn, serverConn := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(serverConn, t)
+		serverConn.Close()
+	}()
+
+	return clientConn, done
+}
+
+func TestRelay(t *testing.T) {
+	card := keycardio.NewMockChannel().
+		Respond([]byte{0x01, 0x02}, apdu.SwOK).
+		Respond(nil, 0x6A82)
+	conn, done := serve(keycardio.NewChannelTransmitter(card))
+
+	client, err := NewClient(conn)
+	require.NoError(t, err)
+
+	resp, err := client.Send(apdu.NewCommand(0x80, 0xF2, 0x00, 0x01, []byte{0xAA}))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x02}, resp.Data)
+	assert.Equal(t, uint16(apdu.SwOK), resp.Sw)
+
+	resp, err = client.Send(apdu.NewCommand(0x00, 0xA4, 0x04, 0x00, []byte{0xBB}))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0x6A82), resp.Sw)
+
+	require.Len(t, card.Sent, 2)
+	assert.Equal(t, uint8(0xF2), card.Sent[0].Ins)
+	assert.Equal(t, []byte{0xAA}, card.Sent[0].Data)
+
+	require.NoError(t, client.Close())
+	assert.NoError(t, <-done)
+}
+
+func TestRelay_RemoteError(t *testing.T) {
+	conn, done := serve(keycardio.TransmitterFunc(func([]byte) ([]byte, error) {
+		return nil, errors.New("card removed")
+	}))
+
+	client, err := NewClient(conn)
+	require.NoError(t, err)
+
+	_, err = client.Send(apdu.NewCommand(0x80, 0xF2, 0x00, 0x00, nil))
+	assert.Equal(t, &RemoteError{Message: "card removed"}, err)
+
+	client.Close()
+	assert.NoError(t, <-done)
+}
+
+func TestRelay_Handshake(t *testing.T) {
+	conn, done := serve(keycardio.TransmitterFunc(func([]byte) ([]byte, error) { return nil, nil }))
+	go writeFrame(conn, frameHello, []byte("HTTP1"))
+	assert.Equal(t, ErrHandshakeFailed, <-done)
+	conn.Close()
+
+	conn, done = serve(keycardio.TransmitterFunc(func([]byte) ([]byte, error) { return nil, nil }))
+	go func() {
+		writeFrame(conn, frameHello, append(append([]byte{}, magic...), Version+1))
+		readFrame(conn)
+	}()
+	assert.Equal(t, errUnsupportedVersion, <-done)
+	conn.Close()
+}
+
+func TestWriteFrame(t *testing.T) {
+	assert.Equal(t, ErrFrameTooLong, writeFrame(nil, frameCommand, make([]byte, MaxFrameLength+1)))
+}
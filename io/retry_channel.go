@@ -0,0 +1,64 @@
+package io
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/types"
+)
+
+// RetryChannel wraps a channel sending commands again when the transport fails,
+// waiting a backoff that doubles after every attempt.
+// Responses with an error status word aren't transport failures and are returned as they are.
+//
+// A command can reach the card even if the transport fails before the response is read,
+// so Retryable must exclude the commands that can't be safely executed twice.
+// When wrapped by a secure channel, a retried command that reached the card
+// makes the next response MAC check fail.
+type RetryChannel struct {
+	c       types.Channel
+	retries int
+	backoff time.Duration
+	sleep   func(time.Duration)
+
+	// Retryable returns true if cmd can be sent again after a transport failure.
+	// A nil Retryable retries every command.
+	Retryable func(cmd *apdu.Command) bool
+}
+
+// NewRetryChannel returns a new RetryChannel sending each command up to retries more times
+// through c, waiting backoff before the first retry.
+func NewRetryChannel(c types.Channel, retries int, backoff time.Duration) *RetryChannel {
+	return &RetryChannel{
+		c:       c,
+		retries: retries,
+		backoff: backoff,
+		sleep:   time.Sleep,
+	}
+}
+
+// Send sends cmd, retrying on transport failures. It returns the error of the last attempt.
+func (c *RetryChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.c.Send(cmd)
+		if err == nil || attempt == c.retries || !c.canRetry(cmd, err) {
+			return resp, err
+		}
+
+		logger.Debug("retrying apdu command", "ins", cmd.Ins, "attempt", attempt+1, "err", err)
+		c.sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (c *RetryChannel) canRetry(cmd *apdu.Command, err error) bool {
+	var badResponse *apdu.ErrBadResponse
+	if errors.As(err, &badResponse) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	return c.Retryable == nil || c.Retryable(cmd)
+}
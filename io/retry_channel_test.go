@@ -0,0 +1,64 @@
+package io
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRetryChannel(c *MockChannel, retries int) (*RetryChannel, *[]time.Duration) {
+	rc := NewRetryChannel(c, retries, 10*time.Millisecond)
+	waits := []time.Duration{}
+	rc.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	return rc, &waits
+}
+
+func TestRetryChannel_Send(t *testing.T) {
+	transportErr := errors.New("transmit failed")
+	c := NewMockChannel().Fail(transportErr).Fail(transportErr).Respond([]byte{0x01}, apdu.SwOK)
+	rc, waits := newTestRetryChannel(c, 3)
+
+	resp, err := rc.Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, resp.Data)
+	assert.Len(t, c.Sent, 3)
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}, *waits)
+}
+
+func TestRetryChannel_GivesUp(t *testing.T) {
+	transportErr := errors.New("transmit failed")
+	c := NewMockChannel().Fail(transportErr).Fail(transportErr).Fail(transportErr)
+	rc, _ := newTestRetryChannel(c, 1)
+
+	_, err := rc.Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	assert.Equal(t, transportErr, err)
+	assert.Len(t, c.Sent, 2)
+}
+
+func TestRetryChannel_NotRetryable(t *testing.T) {
+	transportErr := errors.New("transmit failed")
+	c := NewMockChannel().
+		Fail(transportErr).
+		Fail(context.Canceled).
+		Fail(apdu.NewErrBadResponse(0x6985, "unexpected response"))
+	rc, waits := newTestRetryChannel(c, 3)
+	rc.Retryable = func(cmd *apdu.Command) bool { return cmd.Ins != 0xD4 }
+
+	_, err := rc.Send(apdu.NewCommand(0x80, 0xD4, 0, 0, nil))
+	assert.Equal(t, transportErr, err)
+
+	_, err = rc.Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = rc.Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	assert.Error(t, err)
+
+	assert.Len(t, c.Sent, 3)
+	assert.Empty(t, *waits)
+}
@@ -0,0 +1,70 @@
+package io
+
+import (
+	"errors"
+	"time"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/types"
+)
+
+// ErrTimeout is returned by TimeoutChannel when the wrapped channel doesn't respond in time.
+var ErrTimeout = errors.New("apdu command timed out")
+
+// TimeoutChannel wraps a channel returning ErrTimeout when a command takes longer than its timeout,
+// for transports that can hang and can't be interrupted.
+// The command keeps running in the background: the next command waits for it to complete,
+// within its own timeout, so the wrapped channel never sends two commands at once.
+// Wrapped by a RetryChannel, timed out commands are retried.
+type TimeoutChannel struct {
+	c       types.Channel
+	timeout time.Duration
+	busy    chan struct{}
+}
+
+type timeoutResult struct {
+	resp *apdu.Response
+	err  error
+}
+
+// NewTimeoutChannel returns a new TimeoutChannel sending commands through c.
+// A timeout of 0 disables it.
+func NewTimeoutChannel(c types.Channel, timeout time.Duration) *TimeoutChannel {
+	return &TimeoutChannel{
+		c:       c,
+		timeout: timeout,
+		busy:    make(chan struct{}, 1),
+	}
+}
+
+// Send sends cmd, returning ErrTimeout if the previous command is still running
+// or if cmd doesn't complete before the timeout.
+func (c *TimeoutChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	if c.timeout <= 0 {
+		return c.c.Send(cmd)
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	select {
+	case c.busy <- struct{}{}:
+	case <-timer.C:
+		return nil, ErrTimeout
+	}
+
+	result := make(chan timeoutResult, 1)
+	go func() {
+		resp, err := c.c.Send(cmd)
+		<-c.busy
+		result <- timeoutResult{resp, err}
+	}()
+
+	select {
+	case r := <-result:
+		return r.resp, r.err
+	case <-timer.C:
+		logger.Debug("apdu command timed out", "ins", cmd.Ins, "timeout", c.timeout)
+		return nil, ErrTimeout
+	}
+}
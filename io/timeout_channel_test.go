@@ -0,0 +1,72 @@
+package io
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingChannel blocks every command until release is closed.
+type blockingChannel struct {
+	types.Channel
+	release chan struct{}
+}
+
+func (c *blockingChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	<-c.release
+	return c.Channel.Send(cmd)
+}
+
+func TestTimeoutChannel_Send(t *testing.T) {
+	c := NewMockChannel().Respond([]byte{0x01}, apdu.SwOK).Respond([]byte{0x02}, apdu.SwOK)
+	tc := NewTimeoutChannel(c, time.Second)
+
+	resp, err := tc.Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, resp.Data)
+
+	// without timeout commands are sent directly
+	resp, err = NewTimeoutChannel(c, 0).Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x02}, resp.Data)
+
+	_, err = NewTimeoutChannel(NewMockChannel().Fail(ErrNoMockResponse), time.Second).Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	assert.Equal(t, ErrNoMockResponse, err)
+}
+
+func TestTimeoutChannel_Timeout(t *testing.T) {
+	mc := NewMockChannel().Respond([]byte{0x01}, apdu.SwOK).Respond([]byte{0x02}, apdu.SwOK)
+	bc := &blockingChannel{Channel: mc, release: make(chan struct{})}
+	tc := NewTimeoutChannel(bc, 10*time.Millisecond)
+
+	_, err := tc.Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	assert.Equal(t, ErrTimeout, err)
+
+	// the first command is still running
+	_, err = tc.Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	assert.Equal(t, ErrTimeout, err)
+	assert.Empty(t, mc.Sent)
+
+	close(bc.release)
+	tc.timeout = time.Second
+	resp, err := tc.Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x02}, resp.Data)
+	assert.Len(t, mc.Sent, 2)
+}
+
+func TestTimeoutChannel_Retry(t *testing.T) {
+	transportErr := errors.New("transmit failed")
+	mc := NewMockChannel().Fail(transportErr).Respond([]byte{0x01}, apdu.SwOK)
+	rc, _ := newTestRetryChannel(mc, 1)
+	rc.c = NewTimeoutChannel(mc, time.Second)
+
+	resp, err := rc.Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, resp.Data)
+}
@@ -0,0 +1,41 @@
+package io
+
+import (
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/types"
+)
+
+// TransmitterFunc adapts a function to the Transmitter interface, to plug raw transports
+// like a relay to a remote reader into NewNormalChannel.
+type TransmitterFunc func([]byte) ([]byte, error)
+
+// Transmit calls f(raw).
+func (f TransmitterFunc) Transmit(raw []byte) ([]byte, error) {
+	return f(raw)
+}
+
+// ChannelTransmitter is the reverse of NormalChannel: it's a Transmitter sending raw commands
+// through a Channel, for instance to serve a local card to a remote client sending raw bytes.
+type ChannelTransmitter struct {
+	c types.Channel
+}
+
+// NewChannelTransmitter returns a Transmitter sending commands through c.
+func NewChannelTransmitter(c types.Channel) *ChannelTransmitter {
+	return &ChannelTransmitter{c}
+}
+
+// Transmit parses raw as a command, sends it and returns the serialized response.
+func (t *ChannelTransmitter) Transmit(raw []byte) ([]byte, error) {
+	cmd, err := apdu.ParseCommand(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.c.Send(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return serializeResponse(resp), nil
+}
@@ -0,0 +1,49 @@
+package io
+
+import (
+	"testing"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransmitterFunc(t *testing.T) {
+	var sent []byte
+	c := NewNormalChannel(TransmitterFunc(func(raw []byte) ([]byte, error) {
+		sent = raw
+		return hexutils.HexToBytes("0102 9000"), nil
+	}))
+
+	resp, err := c.Send(apdu.NewCommand(0x80, 0xF2, 0, 0, nil))
+	require.NoError(t, err)
+	assert.Equal(t, hexutils.HexToBytes("80F20000"), sent)
+	assert.Equal(t, []byte{0x01, 0x02}, resp.Data)
+}
+
+func TestChannelTransmitter(t *testing.T) {
+	mc := NewMockChannel().Respond([]byte{0xAA, 0xBB}, apdu.SwOK)
+	tr := NewChannelTransmitter(mc)
+
+	// a NormalChannel over the transmitter behaves like the wrapped channel
+	resp, err := NewNormalChannel(tr).Send(apdu.NewCommand(0x80, 0xCA, 0x01, 0x02, []byte{0x05}))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xAA, 0xBB}, resp.Data)
+	assert.Equal(t, uint16(apdu.SwOK), resp.Sw)
+
+	require.Len(t, mc.Sent, 1)
+	assert.Equal(t, uint8(0xCA), mc.Sent[0].Ins)
+	assert.Equal(t, []byte{0x05}, mc.Sent[0].Data)
+
+	_, err = tr.Transmit([]byte{0x80})
+	assert.Error(t, err)
+
+	// Le only commands keep their Le
+	mc.Respond(nil, apdu.SwOK)
+	_, err = tr.Transmit(hexutils.HexToBytes("00B000000F"))
+	require.NoError(t, err)
+	hasLe, le := mc.Sent[1].Le()
+	assert.True(t, hasLe)
+	assert.Equal(t, uint8(0x0F), le)
+}
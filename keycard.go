@@ -1,3 +1,6 @@
+// Package keycard implements the Keycard applet protocol: applet selection, initialization,
+// pairing, the secure channel and the key management and signing commands, all exposed
+// as methods of CommandSet.
 package keycard
 
 import (
@@ -0,0 +1,88 @@
+// Package lightwallet eases the migration from the lightwallet package of hardware-wallet-go.
+// It forwards the main Keycard commands to keycard.CommandSet as functions and re-exports
+// the types they use, so code written against hardware-wallet-go only needs its imports
+// changed. New code should use the keycard package directly.
+package lightwallet
+
+import (
+	keycard "github.com/status-im/keycard-go"
+	"github.com/status-im/keycard-go/types"
+)
+
+type (
+	// CommandSet is keycard.CommandSet.
+	CommandSet = keycard.CommandSet
+	// SecureChannel is keycard.SecureChannel.
+	SecureChannel = keycard.SecureChannel
+	// Secrets is keycard.Secrets.
+	Secrets = keycard.Secrets
+	// Channel is types.Channel.
+	Channel = types.Channel
+	// ApplicationInfo is types.ApplicationInfo.
+	ApplicationInfo = types.ApplicationInfo
+	// ApplicationStatus is types.ApplicationStatus.
+	ApplicationStatus = types.ApplicationStatus
+	// PairingInfo is types.PairingInfo.
+	PairingInfo = types.PairingInfo
+)
+
+// Errors returned by the forwarded functions, the same values as in the keycard package.
+var (
+	ErrAlreadyInitialized   = keycard.ErrAlreadyInitialized
+	ErrPairingInfoNotSet    = keycard.ErrPairingInfoNotSet
+	ErrSecureChannelNotOpen = keycard.ErrSecureChannelNotOpen
+)
+
+// NewCommandSet returns a CommandSet sending commands through c.
+func NewCommandSet(c Channel) *CommandSet {
+	return keycard.NewCommandSet(c)
+}
+
+// NewSecrets returns the Secrets with the given pin, puk and pairing password.
+func NewSecrets(pin, puk, pairingPass string) (*Secrets, error) {
+	return keycard.NewSecrets(pin, puk, pairingPass)
+}
+
+// Select selects the Keycard applet and returns its application info.
+func Select(cs *CommandSet) (*ApplicationInfo, error) {
+	if err := cs.Select(); err != nil {
+		return nil, err
+	}
+
+	return cs.ApplicationInfo, nil
+}
+
+// Init initializes the card with secrets and returns its InstanceUID, see CommandSet.Init.
+func Init(cs *CommandSet, secrets *Secrets, force bool) ([]byte, error) {
+	return cs.Init(secrets, force)
+}
+
+// Pair pairs with the card using pairingPass and returns the new pairing.
+func Pair(cs *CommandSet, pairingPass string) (*PairingInfo, error) {
+	if err := cs.Pair(pairingPass); err != nil {
+		return nil, err
+	}
+
+	return cs.PairingInfo, nil
+}
+
+// OpenSecureChannel opens a secure channel with pairing.
+func OpenSecureChannel(cs *CommandSet, pairing *PairingInfo) error {
+	if pairing == nil {
+		return ErrPairingInfoNotSet
+	}
+
+	cs.SetPairingInfo(pairing.Key, pairing.Index)
+
+	return cs.OpenSecureChannel()
+}
+
+// VerifyPIN verifies pin through the open secure channel.
+func VerifyPIN(cs *CommandSet, pin string) error {
+	return cs.VerifyPIN(pin)
+}
+
+// GetStatus returns the PIN and PUK retry counters and whether a key is loaded.
+func GetStatus(cs *CommandSet) (*ApplicationStatus, error) {
+	return cs.GetStatusApplication()
+}
@@ -0,0 +1,53 @@
+package lightwallet
+
+import (
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	keycard "github.com/status-im/keycard-go"
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/globalplatform"
+	keycardio "github.com/status-im/keycard-go/io"
+	"github.com/status-im/keycard-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the forwarding functions keep the hardware-wallet-go shapes
+var (
+	_ func(Channel) *CommandSet                         = NewCommandSet
+	_ func(string, string, string) (*Secrets, error)    = NewSecrets
+	_ func(*CommandSet) (*ApplicationInfo, error)       = Select
+	_ func(*CommandSet, *Secrets, bool) ([]byte, error) = Init
+	_ func(*CommandSet, string) (*PairingInfo, error)   = Pair
+	_ func(*CommandSet, *PairingInfo) error             = OpenSecureChannel
+	_ func(*CommandSet, string) error                   = VerifyPIN
+	_ func(*CommandSet) (*ApplicationStatus, error)     = GetStatus
+	_ *keycard.CommandSet                               = (*CommandSet)(nil)
+	_ *types.PairingInfo                                = (*PairingInfo)(nil)
+)
+
+func TestSelectAndInit(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	preInit := append([]byte{types.TagSelectResponsePreInitialized, 0x41}, ethcrypto.FromECDSAPub(&cardKey.PublicKey)...)
+
+	c := keycardio.NewMockChannel().
+		Expect(globalplatform.InsSelect, preInit, apdu.SwOK).
+		Expect(globalplatform.InsSelect, preInit, apdu.SwOK).
+		Expect(keycard.InsInit, nil, keycard.SwInsNotSupported)
+	cs := NewCommandSet(c)
+
+	info, err := Select(cs)
+	require.NoError(t, err)
+	assert.False(t, info.Initialized)
+	assert.Equal(t, cs.ApplicationInfo, info)
+
+	secrets, err := NewSecrets("123456", "123456789012", "KeycardTest")
+	require.NoError(t, err)
+	_, err = Init(cs, secrets, false)
+	assert.Equal(t, ErrAlreadyInitialized, err)
+	assert.Zero(t, c.Pending())
+
+	assert.Equal(t, ErrPairingInfoNotSet, OpenSecureChannel(cs, nil))
+}
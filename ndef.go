@@ -0,0 +1,106 @@
+package keycard
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/globalplatform"
+	"github.com/status-im/keycard-go/identifiers"
+	"github.com/status-im/keycard-go/ndef"
+	"github.com/status-im/keycard-go/types"
+)
+
+const (
+	ndefCapabilityContainerFID = 0xE103
+	ndefCapabilityContainerLen = 15
+	ndefFileControlTag         = 0x04
+)
+
+var ErrInvalidCapabilityContainer = errors.New("invalid NDEF capability container")
+
+// ReadNDEF selects the NDEF applet and reads its NDEF message, as an NFC Forum type 4 tag reader would.
+// The capability container is read first to find the NDEF file and the maximum read size.
+func ReadNDEF(c types.Channel) (*ndef.Message, error) {
+	cmd := globalplatform.NewCommandSelect(identifiers.NdefInstanceAID)
+	cmd.SetLe(0)
+	if _, err := sendOK(c, cmd); err != nil {
+		return nil, err
+	}
+
+	if _, err := sendOK(c, globalplatform.NewCommandSelectFile(ndefCapabilityContainerFID)); err != nil {
+		return nil, err
+	}
+
+	cc, err := sendOK(c, globalplatform.NewCommandReadBinary(0, ndefCapabilityContainerLen))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cc) < ndefCapabilityContainerLen || cc[7] != ndefFileControlTag {
+		return nil, ErrInvalidCapabilityContainer
+	}
+
+	maxRead := binary.BigEndian.Uint16(cc[3:5])
+	if maxRead == 0 {
+		return nil, ErrInvalidCapabilityContainer
+	}
+
+	if maxRead > 0xFF {
+		maxRead = 0xFF
+	}
+
+	fid := binary.BigEndian.Uint16(cc[9:11])
+	if _, err = sendOK(c, globalplatform.NewCommandSelectFile(fid)); err != nil {
+		return nil, err
+	}
+
+	nlen, err := readBinary(c, 0, 2, maxRead)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readBinary(c, 2, binary.BigEndian.Uint16(nlen), maxRead)
+	if err != nil {
+		return nil, err
+	}
+
+	return ndef.ParseMessage(data)
+}
+
+// readBinary reads length bytes at offset of the selected file, maxRead bytes at a time.
+func readBinary(c types.Channel, offset, length, maxRead uint16) ([]byte, error) {
+	data := make([]byte, 0, length)
+	for uint16(len(data)) < length {
+		n := length - uint16(len(data))
+		if n > maxRead {
+			n = maxRead
+		}
+
+		chunk, err := sendOK(c, globalplatform.NewCommandReadBinary(offset+uint16(len(data)), uint8(n)))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(chunk) == 0 || len(chunk) > int(n) {
+			return nil, ErrMalformedResponse
+		}
+
+		data = append(data, chunk...)
+	}
+
+	return data, nil
+}
+
+func sendOK(c types.Channel, cmd *apdu.Command) ([]byte, error) {
+	resp, err := c.Send(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.IsOK() {
+		return nil, apdu.NewErrBadResponse(resp.Sw, "unexpected response")
+	}
+
+	return resp.Data, nil
+}
@@ -0,0 +1,237 @@
+package ndef
+
+import (
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+)
+
+// Type Name Format values of the record header.
+const (
+	TNFEmpty     = 0x00
+	TNFWellKnown = 0x01
+	TNFMedia     = 0x02
+	TNFURI       = 0x03
+	TNFExternal  = 0x04
+	TNFUnknown   = 0x05
+	TNFUnchanged = 0x06
+)
+
+// Well known record types.
+const (
+	RecordTypeURI  = "U"
+	RecordTypeText = "T"
+)
+
+const (
+	flagME  = 0x40
+	flagCF  = 0x20
+	flagSR  = 0x10
+	flagIL  = 0x08
+	maskTNF = 0x07
+
+	textUTF16   = 0x80
+	textLangLen = 0x3F
+)
+
+var (
+	ErrMalformedRecord      = errors.New("malformed NDEF record")
+	ErrChunkedRecord        = errors.New("chunked NDEF records are not supported")
+	ErrUnexpectedRecordType = errors.New("unexpected NDEF record type")
+)
+
+// uriPrefixes are the abbreviations of the URI identifier code, the first byte of a URI record payload.
+var uriPrefixes = []string{
+	"",
+	"http://www.",
+	"https://www.",
+	"http://",
+	"https://",
+	"tel:",
+	"mailto:",
+	"ftp://anonymous:anonymous@",
+	"ftp://ftp.",
+	"ftps://",
+	"sftp://",
+	"smb://",
+	"nfs://",
+	"ftp://",
+	"dav://",
+	"news:",
+	"telnet://",
+	"imap:",
+	"rtsp://",
+	"urn:",
+	"pop:",
+	"sip:",
+	"sips:",
+	"tftp:",
+	"btspp://",
+	"btl2cap://",
+	"btgoep://",
+	"tcpobex://",
+	"irdaobex://",
+	"file://",
+	"urn:epc:id:",
+	"urn:epc:tag:",
+	"urn:epc:pat:",
+	"urn:epc:raw:",
+	"urn:epc:",
+	"urn:nfc:",
+}
+
+// Record is a single NDEF record.
+type Record struct {
+	TNF     uint8
+	Type    []byte
+	ID      []byte
+	Payload []byte
+}
+
+// Message is a sequence of NDEF records.
+type Message struct {
+	Records []*Record
+}
+
+// ParseMessage parses an NDEF message, the content of the NDEF file without its length prefix.
+func ParseMessage(data []byte) (*Message, error) {
+	msg := &Message{}
+	for len(data) > 0 {
+		record, n, err := parseRecord(data)
+		if err != nil {
+			return nil, err
+		}
+
+		msg.Records = append(msg.Records, record)
+		if data[0]&flagME != 0 {
+			break
+		}
+
+		data = data[n:]
+	}
+
+	if len(msg.Records) == 0 {
+		return nil, ErrMalformedRecord
+	}
+
+	return msg, nil
+}
+
+func parseRecord(data []byte) (*Record, int, error) {
+	if len(data) < 3 {
+		return nil, 0, ErrMalformedRecord
+	}
+
+	header := data[0]
+	if header&flagCF != 0 {
+		return nil, 0, ErrChunkedRecord
+	}
+
+	typeLength := int(data[1])
+	offset := 2
+
+	var payloadLength int
+	if header&flagSR != 0 {
+		payloadLength = int(data[offset])
+		offset++
+	} else {
+		if len(data) < offset+4 {
+			return nil, 0, ErrMalformedRecord
+		}
+
+		payloadLength = int(binary.BigEndian.Uint32(data[offset:]))
+		offset += 4
+	}
+
+	idLength := 0
+	if header&flagIL != 0 {
+		if len(data) < offset+1 {
+			return nil, 0, ErrMalformedRecord
+		}
+
+		idLength = int(data[offset])
+		offset++
+	}
+
+	if payloadLength < 0 || len(data)-offset < typeLength+idLength+payloadLength {
+		return nil, 0, ErrMalformedRecord
+	}
+
+	r := &Record{TNF: header & maskTNF}
+	r.Type = data[offset : offset+typeLength]
+	offset += typeLength
+	r.ID = data[offset : offset+idLength]
+	offset += idLength
+	r.Payload = data[offset : offset+payloadLength]
+	offset += payloadLength
+
+	return r, offset, nil
+}
+
+// IsWellKnown returns true if the record is a well known record of type typ.
+func (r *Record) IsWellKnown(typ string) bool {
+	return r.TNF == TNFWellKnown && string(r.Type) == typ
+}
+
+// URI returns the URI of a well known URI record.
+func (r *Record) URI() (string, error) {
+	if !r.IsWellKnown(RecordTypeURI) {
+		return "", ErrUnexpectedRecordType
+	}
+
+	if len(r.Payload) == 0 {
+		return "", ErrMalformedRecord
+	}
+
+	prefix := ""
+	if int(r.Payload[0]) < len(uriPrefixes) {
+		prefix = uriPrefixes[r.Payload[0]]
+	}
+
+	return prefix + string(r.Payload[1:]), nil
+}
+
+// Text returns the text and its language code of a well known Text record.
+func (r *Record) Text() (string, string, error) {
+	if !r.IsWellKnown(RecordTypeText) {
+		return "", "", ErrUnexpectedRecordType
+	}
+
+	if len(r.Payload) == 0 {
+		return "", "", ErrMalformedRecord
+	}
+
+	status := r.Payload[0]
+	langLength := int(status & textLangLen)
+	if len(r.Payload) < 1+langLength {
+		return "", "", ErrMalformedRecord
+	}
+
+	lang := string(r.Payload[1 : 1+langLength])
+	text := r.Payload[1+langLength:]
+
+	if status&textUTF16 == 0 {
+		return string(text), lang, nil
+	}
+
+	if len(text)%2 != 0 {
+		return "", "", ErrMalformedRecord
+	}
+
+	// UTF-16 text is big endian unless it starts with a little endian BOM
+	var order binary.ByteOrder = binary.BigEndian
+	if len(text) >= 2 && text[0] == 0xFF && text[1] == 0xFE {
+		order = binary.LittleEndian
+	}
+
+	units := make([]uint16, 0, len(text)/2)
+	for i := 0; i < len(text); i += 2 {
+		units = append(units, order.Uint16(text[i:]))
+	}
+
+	if len(units) > 0 && units[0] == 0xFEFF {
+		units = units[1:]
+	}
+
+	return string(utf16.Decode(units)), lang, nil
+}
@@ -0,0 +1,82 @@
+package ndef
+
+import (
+	"testing"
+
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMessage(t *testing.T) {
+	// https://keycard.tech followed by an en Text record "hi"
+	data := hexutils.HexToBytes("91 01 0D 55 04 6B 65 79 63 61 72 64 2E 74 65 63 68 51 01 05 54 02 65 6E 68 69")
+
+	msg, err := ParseMessage(data)
+	require.NoError(t, err)
+	require.Len(t, msg.Records, 2)
+
+	uri, err := msg.Records[0].URI()
+	require.NoError(t, err)
+	assert.Equal(t, "https://keycard.tech", uri)
+
+	_, _, err = msg.Records[0].Text()
+	assert.Equal(t, ErrUnexpectedRecordType, err)
+
+	text, lang, err := msg.Records[1].Text()
+	require.NoError(t, err)
+	assert.Equal(t, "hi", text)
+	assert.Equal(t, "en", lang)
+
+	// records after the one with ME set are ignored
+	msg, err = ParseMessage(append(data[17:], 0xFF))
+	require.NoError(t, err)
+	assert.Len(t, msg.Records, 1)
+}
+
+func TestParseMessageLongRecord(t *testing.T) {
+	// not short record, 4 bytes payload length, with ID
+	data := hexutils.HexToBytes("C9 01 00 00 00 02 01 55 AA 00 78")
+
+	msg, err := ParseMessage(data)
+	require.NoError(t, err)
+	require.Len(t, msg.Records, 1)
+	assert.Equal(t, []byte{0xAA}, msg.Records[0].ID)
+
+	uri, err := msg.Records[0].URI()
+	require.NoError(t, err)
+	assert.Equal(t, "x", uri)
+}
+
+func TestParseMessageErrors(t *testing.T) {
+	_, err := ParseMessage(nil)
+	assert.Equal(t, ErrMalformedRecord, err)
+
+	_, err = ParseMessage(hexutils.HexToBytes("D1 01 0D 55 04"))
+	assert.Equal(t, ErrMalformedRecord, err)
+
+	_, err = ParseMessage(hexutils.HexToBytes("B1 01 01 55 04"))
+	assert.Equal(t, ErrChunkedRecord, err)
+}
+
+func TestRecordTextUTF16(t *testing.T) {
+	r := &Record{
+		TNF:     TNFWellKnown,
+		Type:    []byte(RecordTypeText),
+		Payload: hexutils.HexToBytes("82 65 6E FE FF 00 68 00 69"),
+	}
+
+	text, lang, err := r.Text()
+	require.NoError(t, err)
+	assert.Equal(t, "hi", text)
+	assert.Equal(t, "en", lang)
+
+	r.Payload = hexutils.HexToBytes("82 65 6E FF FE 68 00 69 00")
+	text, _, err = r.Text()
+	require.NoError(t, err)
+	assert.Equal(t, "hi", text)
+
+	r.Payload = hexutils.HexToBytes("05 65 6E")
+	_, _, err = r.Text()
+	assert.Equal(t, ErrMalformedRecord, err)
+}
@@ -0,0 +1,57 @@
+package keycard
+
+import (
+	"testing"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/globalplatform"
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/status-im/keycard-go/identifiers"
+	keycardio "github.com/status-im/keycard-go/io"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadNDEF(t *testing.T) {
+	message := hexutils.HexToBytes("D1 01 0D 55 04 6B 65 79 63 61 72 64 2E 74 65 63 68")
+	// max read size of 8 bytes, NDEF file E104
+	cc := hexutils.HexToBytes("00 0F 20 00 08 00 08 04 06 E1 04 00 FF 00 FF")
+
+	c := keycardio.NewMockChannel().
+		Respond(nil, apdu.SwOK).
+		Respond(nil, apdu.SwOK).
+		Respond(cc, apdu.SwOK).
+		Respond(nil, apdu.SwOK).
+		Respond([]byte{0x00, byte(len(message))}, apdu.SwOK).
+		Respond(message[:8], apdu.SwOK).
+		Respond(message[8:16], apdu.SwOK).
+		Respond(message[16:], apdu.SwOK)
+
+	msg, err := ReadNDEF(c)
+	require.NoError(t, err)
+	require.Len(t, msg.Records, 1)
+	uri, err := msg.Records[0].URI()
+	require.NoError(t, err)
+	assert.Equal(t, "https://keycard.tech", uri)
+
+	assert.Equal(t, identifiers.NdefInstanceAID, c.Sent[0].Data)
+	assert.Equal(t, []byte{0xE1, 0x04}, c.Sent[3].Data)
+	read := c.Sent[7]
+	assert.Equal(t, uint8(globalplatform.InsReadBinary), read.Ins)
+	assert.Equal(t, uint8(18), read.P2)
+	_, le := read.Le()
+	assert.Equal(t, uint8(1), le)
+}
+
+func TestReadNDEFErrors(t *testing.T) {
+	c := newMockChannel(SwFileNotFound)
+	_, err := ReadNDEF(c)
+	assert.Error(t, err)
+
+	c = keycardio.NewMockChannel().
+		Respond(nil, apdu.SwOK).
+		Respond(nil, apdu.SwOK).
+		Respond([]byte{0x00, 0x0F}, apdu.SwOK)
+	_, err = ReadNDEF(c)
+	assert.Equal(t, ErrInvalidCapabilityContainer, err)
+}
@@ -0,0 +1,132 @@
+package keycard
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/status-im/keycard-go/types"
+)
+
+var ErrPairingNotFound = errors.New("pairing not found")
+
+// PairingStore stores pairings by the InstanceUID of the card they belong to.
+type PairingStore interface {
+	// Get returns the pairing of the card with instanceUID, or ErrPairingNotFound.
+	Get(instanceUID []byte) (*types.PairingInfo, error)
+	// Put stores the pairing of the card with instanceUID, replacing any previous one.
+	Put(instanceUID []byte, pairing *types.PairingInfo) error
+	// Delete removes the pairing of the card with instanceUID.
+	Delete(instanceUID []byte) error
+}
+
+// FilePairingStore is a PairingStore keeping all the pairings in a JSON file.
+type FilePairingStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFilePairingStore returns a FilePairingStore backed by the file at path.
+// The file is created on the first Put.
+func NewFilePairingStore(path string) *FilePairingStore {
+	return &FilePairingStore{path: path}
+}
+
+func (s *FilePairingStore) Get(instanceUID []byte) (*types.PairingInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pairings, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	pairing, ok := pairings[hex.EncodeToString(instanceUID)]
+	if !ok {
+		return nil, ErrPairingNotFound
+	}
+
+	return pairing, nil
+}
+
+func (s *FilePairingStore) Put(instanceUID []byte, pairing *types.PairingInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pairings, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	pairings[hex.EncodeToString(instanceUID)] = pairing
+
+	return s.save(pairings)
+}
+
+func (s *FilePairingStore) Delete(instanceUID []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pairings, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	delete(pairings, hex.EncodeToString(instanceUID))
+
+	return s.save(pairings)
+}
+
+func (s *FilePairingStore) load() (map[string]*types.PairingInfo, error) {
+	pairings := make(map[string]*types.PairingInfo)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return pairings, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &pairings); err != nil {
+		return nil, err
+	}
+
+	return pairings, nil
+}
+
+// save writes the pairings to a temporary file and renames it,
+// so a failed write never leaves a truncated store behind.
+func (s *FilePairingStore) save(pairings map[string]*types.PairingInfo) error {
+	data, err := json.MarshalIndent(pairings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,66 @@
+package keycard
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/status-im/keycard-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePairingStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pairings.json")
+	s := NewFilePairingStore(path)
+
+	uid := []byte{0x01, 0x02}
+	_, err := s.Get(uid)
+	assert.Equal(t, ErrPairingNotFound, err)
+
+	pairing := &types.PairingInfo{Key: []byte{0xAA, 0xBB}, Index: 1}
+	require.NoError(t, s.Put(uid, pairing))
+	require.NoError(t, s.Put([]byte{0x03}, &types.PairingInfo{Key: []byte{0xCC}, Index: 2}))
+
+	// a new store reads the pairings back from the file
+	s = NewFilePairingStore(path)
+	stored, err := s.Get(uid)
+	require.NoError(t, err)
+	assert.Equal(t, pairing, stored)
+
+	require.NoError(t, s.Delete(uid))
+	_, err = s.Get(uid)
+	assert.Equal(t, ErrPairingNotFound, err)
+
+	stored, err = s.Get([]byte{0x03})
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.Index)
+}
+
+func TestFilePairingStore_Reconnect(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	card := &fakeCard{key: cardKey, pairingKey: bytes.Repeat([]byte{0x01}, 32)}
+	store := NewFilePairingStore(filepath.Join(t.TempDir(), "pairings.json"))
+
+	// a previous session paired and stored the pairing
+	cs := NewCommandSet(card)
+	require.NoError(t, cs.Select())
+	require.NoError(t, store.Put(cs.ApplicationInfo.InstanceUID, &types.PairingInfo{Key: card.pairingKey, Index: 1}))
+
+	cs = NewCommandSet(card)
+	require.NoError(t, cs.Select())
+	assert.Equal(t, ErrPairingInfoNotSet, cs.OpenSecureChannel())
+
+	pairing, err := store.Get(cs.ApplicationInfo.InstanceUID)
+	require.NoError(t, err)
+	cs.SetPairingInfo(pairing.Key, pairing.Index)
+	require.NoError(t, cs.OpenSecureChannel())
+
+	card.response = hexutils.HexToBytes("A3 09 02 01 03 02 01 05 01 01 00 90 00")
+	status, err := cs.GetStatusApplication()
+	require.NoError(t, err)
+	assert.Equal(t, 3, status.PinRetryCount)
+}
@@ -2,19 +2,22 @@ package keycard
 
 import (
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"math/big"
 
 	"github.com/status-im/keycard-go/crypto"
-	"golang.org/x/crypto/pbkdf2"
-	"golang.org/x/text/unicode/norm"
+	"github.com/tyler-smith/go-bip39"
 )
 
 const (
 	maxPukNumber = int64(999999999999)
 	maxPinNumber = int64(999999)
+
+	pinLength  = 6
+	pukLength  = 12
+	seedLength = 64
 )
 
 // Secrets contains the secret data needed to pair a client with a card.
@@ -25,28 +28,84 @@ type Secrets struct {
 	pairingToken []byte
 }
 
-func NewSecrets(pin, puk, pairingPass string) *Secrets {
-	return &Secrets{
-		pin:          pin,
-		puk:          puk,
-		pairingPass:  pairingPass,
-		pairingToken: generatePairingToken(pairingPass),
+var ErrEmptyPairingPass = errors.New("pairing password cannot be empty")
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// NewSecrets returns the Secrets with the given pin, puk and pairing password.
+// The pin must be 6 digits and the puk 12 digits.
+func NewSecrets(pin, puk, pairingPass string) (*Secrets, error) {
+	s := &Secrets{
+		pin:         pin,
+		puk:         puk,
+		pairingPass: pairingPass,
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
 	}
+
+	s.pairingToken = generatePairingToken(pairingPass)
+
+	return s, nil
+}
+
+// Validate checks the secrets against the constraints of the applet, returning ErrInvalidPIN,
+// ErrInvalidPUK or ErrEmptyPairingPass for the first invalid one.
+func (s *Secrets) Validate() error {
+	if !isDigits(s.pin, pinLength) {
+		return ErrInvalidPIN
+	}
+
+	if !isDigits(s.puk, pukLength) {
+		return ErrInvalidPUK
+	}
+
+	if s.pairingPass == "" {
+		return ErrEmptyPairingPass
+	}
+
+	return nil
+}
+
+// SecretsFromMnemonic returns the Secrets to initialize a card and the BIP39 seed of mnemonic
+// and passphrase, to load with LoadSeed once the card is initialized.
+// It fails with ErrInvalidMnemonic if mnemonic has unknown words or a wrong checksum.
+func SecretsFromMnemonic(mnemonic, passphrase, pin, puk, pairingPass string) (*Secrets, []byte, error) {
+	secrets, err := NewSecrets(pin, puk, pairingPass)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seed, err := seedFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return secrets, seed, nil
+}
+
+func seedFromMnemonic(mnemonic, passphrase string) ([]byte, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMnemonic, err)
+	}
+
+	return seed, nil
 }
 
-// GenerateSecrets generate a new Secrets with  random puk and pairing password.
+// GenerateSecrets generates a new Secrets with random pin, puk and pairing password.
 func GenerateSecrets() (*Secrets, error) {
 	pairingPass, err := generatePairingPass()
 	if err != nil {
 		return nil, err
 	}
 
-	puk, err := rand.Int(rand.Reader, big.NewInt(maxPukNumber))
+	puk, err := rand.Int(rand.Reader, big.NewInt(maxPukNumber+1))
 	if err != nil {
 		return nil, err
 	}
 
-	pin, err := rand.Int(rand.Reader, big.NewInt(maxPinNumber))
+	pin, err := rand.Int(rand.Reader, big.NewInt(maxPinNumber+1))
 	if err != nil {
 		return nil, err
 	}
@@ -90,5 +149,5 @@ func generatePairingPass() (string, error) {
 }
 
 func generatePairingToken(pass string) []byte {
-	return pbkdf2.Key(norm.NFKD.Bytes([]byte(pass)), norm.NFKD.Bytes([]byte(crypto.PairingTokenSalt)), 50000, 32, sha256.New)
+	return crypto.GeneratePairingToken(pass, crypto.PairingTokenIterations)
 }
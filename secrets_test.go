@@ -0,0 +1,63 @@
+package keycard
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSecrets(t *testing.T) {
+	secrets, err := NewSecrets("123456", "123456789012", "KeycardTest")
+	require.NoError(t, err)
+	assert.Equal(t, "123456", secrets.Pin())
+	assert.Equal(t, "123456789012", secrets.Puk())
+	assert.Equal(t, "KeycardTest", secrets.PairingPass())
+	assert.Len(t, secrets.PairingToken(), 32)
+
+	_, err = NewSecrets("12345", "123456789012", "KeycardTest")
+	assert.Equal(t, ErrInvalidPIN, err)
+	_, err = NewSecrets("123456", "12345678901a", "KeycardTest")
+	assert.Equal(t, ErrInvalidPUK, err)
+	_, err = NewSecrets("123456", "123456789012", "")
+	assert.Equal(t, ErrEmptyPairingPass, err)
+}
+
+func TestGenerateSecrets(t *testing.T) {
+	secrets, err := GenerateSecrets()
+	require.NoError(t, err)
+	assert.True(t, isDigits(secrets.Pin(), pinLength))
+	assert.True(t, isDigits(secrets.Puk(), pukLength))
+	assert.NotEmpty(t, secrets.PairingPass())
+	assert.Equal(t, generatePairingToken(secrets.PairingPass()), secrets.PairingToken())
+}
+
+func TestSecrets_Validate(t *testing.T) {
+	generated, err := GenerateSecrets()
+	require.NoError(t, err)
+	assert.NoError(t, generated.Validate())
+
+	assert.Equal(t, ErrInvalidPIN, (&Secrets{pin: "12345", puk: "123456789012", pairingPass: "pass"}).Validate())
+	assert.Equal(t, ErrInvalidPUK, (&Secrets{pin: "123456", puk: "12345678901a", pairingPass: "pass"}).Validate())
+	assert.Equal(t, ErrEmptyPairingPass, (&Secrets{pin: "123456", puk: "123456789012"}).Validate())
+}
+
+func TestSecretsFromMnemonic(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	secrets, seed, err := SecretsFromMnemonic(mnemonic, "TREZOR", "123456", "123456789012", "KeycardTest")
+	require.NoError(t, err)
+	assert.Equal(t, "123456", secrets.Pin())
+	assert.Equal(t, "C55257C360C07C72029AEBC1B53C05ED0362ADA38EAD3E3E9EFA3708E53495531F09A6987599D18264C1E1C92F2CF141630C7A3C4AB7C81B2F001698E7463B04", hexutils.BytesToHex(seed))
+
+	// wrong checksum
+	_, _, err = SecretsFromMnemonic(strings.Replace(mnemonic, "about", "abandon", 1), "", "123456", "123456789012", "KeycardTest")
+	assert.ErrorIs(t, err, ErrInvalidMnemonic)
+
+	_, _, err = SecretsFromMnemonic("not a mnemonic", "", "123456", "123456789012", "KeycardTest")
+	assert.ErrorIs(t, err, ErrInvalidMnemonic)
+
+	_, _, err = SecretsFromMnemonic(mnemonic, "", "1234", "123456789012", "KeycardTest")
+	assert.Equal(t, ErrInvalidPIN, err)
+}
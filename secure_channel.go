@@ -2,10 +2,16 @@ package keycard
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/ecdsa"
+	"crypto/rand"
 	"errors"
+	"io"
+	"sync"
 
 	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/status-im/keycard-go/apdu"
 	"github.com/status-im/keycard-go/crypto"
 	"github.com/status-im/keycard-go/globalplatform"
@@ -13,31 +19,80 @@ import (
 	"github.com/status-im/keycard-go/types"
 )
 
+// ErrInvalidResponseMAC is returned when the MAC of a secure channel response doesn't match its data.
 var ErrInvalidResponseMAC = errors.New("invalid response MAC")
 
+// ErrInvalidCardPublicKey is returned when the card public key is not an uncompressed secp256k1 point.
+var ErrInvalidCardPublicKey = errors.New("invalid card public key")
+
+// ErrSecureChannelExhausted is returned when a session reached MaxSecureChannelCommands.
+// The channel is closed and must be opened again with OpenSecureChannel.
+var ErrSecureChannelExhausted = errors.New("secure channel session exhausted, open a new one")
+
+// MaxSecureChannelCommands is the number of commands sent in a secure channel session before
+// Send asks for a new one. The IV is not a counter that can wrap but the MAC of the previous
+// message, so it only repeats by collision: the limit keeps sessions far below the 2^64
+// messages where a 128 bits MAC collision becomes likely.
+const MaxSecureChannelCommands = 1 << 32
+
+// ErrInvalidInitDataLength is returned by OneShotEncrypt when the encrypted INIT data
+// doesn't have the length the applet expects, so it's never sent.
+var ErrInvalidInitDataLength = errors.New("invalid encrypted init data length")
+
+// initDataLength is the length of the INIT data: the public key length and the uncompressed
+// public key, the IV, and 6 bytes PIN, 12 bytes PUK and 32 bytes pairing token padded to 64 bytes.
+const initDataLength = 1 + 65 + aes.BlockSize + 64
+
+// SecureChannel encrypts and MACs the commands sent to the card once opened.
+// It's safe for concurrent use: since every command changes the IV of the next one,
+// commands are sent one at a time.
 type SecureChannel struct {
+	mu        sync.Mutex
 	c         types.Channel
 	open      bool
 	secret    []byte
 	publicKey *ecdsa.PublicKey
+	random    io.Reader
 	encKey    []byte
 	macKey    []byte
 	iv        []byte
+	commands  uint64
+	// AES ciphers of encKey and macKey, created once per session
+	encCipher cipher.Block
+	macCipher cipher.Block
 }
 
 func NewSecureChannel(c types.Channel) *SecureChannel {
 	return &SecureChannel{
-		c: c,
+		c:      c,
+		random: rand.Reader,
 	}
 }
 
+// SetRandom sets the source of the ephemeral keys generated by GenerateSecret, crypto/rand by default.
+func (sc *SecureChannel) SetRandom(r io.Reader) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.random = r
+}
+
+// GenerateSecret generates a new ephemeral key and the ECDH secret shared with the card public key.
+// On failure, the previous key and secret are cleared so they can't be used with another card.
 func (sc *SecureChannel) GenerateSecret(cardPubKeyData []byte) error {
-	key, err := ethcrypto.GenerateKey()
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.publicKey = nil
+	sc.secret = nil
+
+	// checked first, an off curve key would only show up later as a MAC mismatch
+	cardPubKey, err := ethcrypto.UnmarshalPubkey(cardPubKeyData)
 	if err != nil {
-		return err
+		return ErrInvalidCardPublicKey
 	}
 
-	cardPubKey, err := ethcrypto.UnmarshalPubkey(cardPubKeyData)
+	key, err := generateKey(sc.random)
 	if err != nil {
 		return err
 	}
@@ -48,38 +103,93 @@ func (sc *SecureChannel) GenerateSecret(cardPubKeyData []byte) error {
 	return nil
 }
 
+// Reset closes the channel and clears the session keys.
+// The ECDH secret is kept, so the channel can be opened again with OpenSecureChannel.
 func (sc *SecureChannel) Reset() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.reset()
+}
+
+func (sc *SecureChannel) reset() {
 	sc.open = false
+	sc.commands = 0
+	sc.iv = nil
+	sc.encKey = nil
+	sc.macKey = nil
+	sc.encCipher = nil
+	sc.macCipher = nil
+}
+
+// Close closes the channel, zeroing the session keys and the ECDH secret so they don't linger
+// in memory. The AES key schedules of the cached ciphers can't be zeroed: they are only dropped
+// and left to the garbage collector. The applet must be selected again before opening a new
+// secure channel.
+func (sc *SecureChannel) Close() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	zero(sc.iv)
+	zero(sc.encKey)
+	zero(sc.macKey)
+	zero(sc.secret)
+
+	sc.reset()
+	sc.secret = nil
+	sc.publicKey = nil
 }
 
 func (sc *SecureChannel) Init(iv, encKey, macKey []byte) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
 	sc.iv = iv
 	sc.encKey = encKey
 	sc.macKey = macKey
+	sc.encCipher = nil
+	sc.macCipher = nil
+	sc.commands = 0
 	sc.open = true
 }
 
 func (sc *SecureChannel) Secret() []byte {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
 	return sc.secret
 }
 
 func (sc *SecureChannel) PublicKey() *ecdsa.PublicKey {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
 	return sc.publicKey
 }
 
 func (sc *SecureChannel) RawPublicKey() []byte {
-	return ethcrypto.FromECDSAPub(sc.publicKey)
+	return ethcrypto.FromECDSAPub(sc.PublicKey())
 }
 
 func (sc *SecureChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
 	if sc.open {
-		encData, err := crypto.EncryptData(cmd.Data, sc.encKey, sc.iv)
-		if err != nil {
+		if sc.commands >= MaxSecureChannelCommands {
+			sc.reset()
+			return nil, ErrSecureChannelExhausted
+		}
+
+		sc.commands++
+
+		if err := sc.initCiphers(); err != nil {
 			return nil, err
 		}
 
+		encData := crypto.EncryptDataWithCipher(cmd.Data, sc.encCipher, sc.iv)
 		meta := []byte{cmd.Cla, cmd.Ins, cmd.P1, cmd.P2, byte(len(encData) + 16), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
-		if err = sc.updateIV(meta, encData); err != nil {
+		if err := sc.updateIV(meta, encData); err != nil {
 			return nil, err
 		}
 
@@ -94,28 +204,43 @@ func (sc *SecureChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
 
 	if sc.open {
 		if resp.Sw != globalplatform.SwOK {
+			if resp.Sw == SwSecurityConditionNotSatisfied {
+				// the card closed the session on its side
+				sc.reset()
+			}
+
 			return nil, apdu.NewErrBadResponse(resp.Sw, "unexpected sw in secure channel")
 		}
 
+		// the MAC followed by at least one block of ciphertext
+		if len(resp.Data) < 2*aes.BlockSize || (len(resp.Data)-aes.BlockSize)%aes.BlockSize != 0 {
+			sc.reset()
+			return nil, ErrInvalidResponseMAC
+		}
+
 		rmeta := []byte{byte(len(resp.Data)), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
 		rmac := resp.Data[:len(sc.iv)]
 		rdata := resp.Data[len(sc.iv):]
+		iv := sc.iv
 
-		plainData, err := crypto.DecryptData(rdata, sc.encKey, sc.iv)
-
-		if err != nil {
-			return nil, err
-		}
-
-		if err = sc.updateIV(rmeta, rdata); err != nil {
-			return nil, err
+		// the MAC is verified before touching the ciphertext
+		if err := sc.updateIV(rmeta, rdata); err != nil {
+			sc.reset()
+			return nil, ErrInvalidResponseMAC
 		}
 
 		if !bytes.Equal(sc.iv, rmac) {
+			// the IV chain is out of sync with the card, no further command can succeed
+			sc.reset()
 			return nil, ErrInvalidResponseMAC
 		}
 
-		logger.Debug("apdu response decrypted", "hex", hexutils.BytesToHexWithSpaces(plainData))
+		plainData, err := crypto.DecryptDataWithCipher(rdata, sc.encCipher, iv)
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Trace("apdu response decrypted", "hex", log.Lazy{Fn: func() string { return hexutils.BytesToHexWithSpaces(plainData) }})
 		return apdu.ParseResponse(plainData)
 	} else {
 		return resp, nil
@@ -123,21 +248,101 @@ func (sc *SecureChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
 
 }
 
+// SendPlain sends cmd as is through the underlying channel, even if the secure channel is open.
+// The command and its response are neither encrypted nor authenticated, so they can be read
+// and changed by anyone on the connection: it must not carry secrets or be trusted for
+// decisions. Commands the applet only accepts through the secure channel fail.
+// The session IV is not touched, but selecting an applet, even the Keycard one, makes the card
+// close the session: Reset must then be called and the channel opened again.
+func (sc *SecureChannel) SendPlain(cmd *apdu.Command) (*apdu.Response, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return sc.c.Send(cmd)
+}
+
+// IsOpen returns true if the session keys are set and the channel wasn't reset since,
+// by the client or because the card closed the session or answered with a wrong MAC.
+func (sc *SecureChannel) IsOpen() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return sc.open
+}
+
+func (sc *SecureChannel) initCiphers() error {
+	if sc.encCipher != nil {
+		return nil
+	}
+
+	encCipher, err := aes.NewCipher(sc.encKey)
+	if err != nil {
+		return err
+	}
+
+	macCipher, err := aes.NewCipher(sc.macKey)
+	if err != nil {
+		return err
+	}
+
+	sc.encCipher = encCipher
+	sc.macCipher = macCipher
+
+	return nil
+}
+
 func (sc *SecureChannel) updateIV(meta, data []byte) error {
-	mac, err := crypto.CalculateMac(meta, data, sc.macKey)
+	iv, err := crypto.CalculateMacWithCipher(meta, data, sc.macCipher)
 	if err != nil {
 		return err
 	}
 
-	sc.iv = mac
+	sc.iv = iv
 
 	return nil
 }
 
+// OneShotEncrypt encrypts the INIT data: the PIN, the PUK and the pairing token, with the
+// ISO 7816-4 padding the applet removes after decryption. Without padding the 50 bytes
+// of secrets wouldn't fill the AES blocks, so every firmware version expects it.
+// It fails with ErrInvalidInitDataLength if the result is not the length the applet expects.
 func (sc *SecureChannel) OneShotEncrypt(secrets *Secrets) ([]byte, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
 	pubKeyData := ethcrypto.FromECDSAPub(sc.publicKey)
 	data := append([]byte(secrets.Pin()), []byte(secrets.Puk())...)
 	data = append(data, secrets.PairingToken()...)
 
-	return crypto.OneShotEncrypt(pubKeyData, sc.secret, data)
+	encrypted, err := crypto.OneShotEncrypt(pubKeyData, sc.secret, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encrypted) != initDataLength {
+		return nil, ErrInvalidInitDataLength
+	}
+
+	return encrypted, nil
+}
+
+// generateKey reads private keys from r until one is valid. Unlike ecdsa.GenerateKey, the key
+// only depends on the bytes read, so a fixed source always gives the same key.
+func generateKey(r io.Reader) (*ecdsa.PrivateKey, error) {
+	d := make([]byte, 32)
+	for {
+		if _, err := io.ReadFull(r, d); err != nil {
+			return nil, err
+		}
+
+		if key, err := ethcrypto.ToECDSA(d); err == nil {
+			return key, nil
+		}
+	}
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
 }
@@ -1,12 +1,24 @@
 package keycard
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
 	"errors"
 	"testing"
 
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/crypto"
+	"github.com/status-im/keycard-go/globalplatform"
 	"github.com/status-im/keycard-go/hexutils"
+	keycardio "github.com/status-im/keycard-go/io"
+	"github.com/status-im/keycard-go/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type fakeChannel struct {
@@ -18,6 +30,142 @@ func (fc *fakeChannel) Send(cmd *apdu.Command) (*apdu.Response, error) {
 	return nil, errors.New("test error")
 }
 
+// fakeCard answers secure channel commands with an encrypted and MACed response,
+// or with 6982 if it can't verify the command MAC with its session keys.
+// If key is set, it also answers SELECT and OPEN SECURE CHANNEL, deriving the session keys
+// from pairingKey like a card would, or rejecting the pairing index if it's nil.
+// If pairingPass or pairingToken is set, PAIR replaces pairingKey. INIT stores the pairing token.
+type fakeCard struct {
+	key          *ecdsa.PrivateKey
+	pairingKey   []byte
+	pairingPass  string
+	pairingToken []byte
+	encKey       []byte
+	macKey       []byte
+	response     []byte
+	tamper       func([]byte)
+}
+
+func (fc *fakeCard) Send(cmd *apdu.Command) (*apdu.Response, error) {
+	if fc.key != nil {
+		switch cmd.Ins {
+		case globalplatform.InsSelect:
+			return apdu.ParseResponse(append(applicationInfoResponse(ethcrypto.FromECDSAPub(&fc.key.PublicKey)), 0x90, 0x00))
+		case InsOpenSecureChannel:
+			return fc.openSecureChannel(cmd)
+		case InsPair:
+			if fc.pairingPass != "" || fc.pairingToken != nil {
+				return fc.pair(cmd)
+			}
+		case InsInit:
+			return fc.init(cmd)
+		}
+	}
+
+	iv := cmd.Data[:16]
+	meta := []byte{cmd.Cla, cmd.Ins, cmd.P1, cmd.P2, byte(len(cmd.Data)), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	mac, err := crypto.CalculateMac(meta, cmd.Data[16:], fc.macKey)
+	if err != nil || !bytes.Equal(mac, iv) {
+		return apdu.ParseResponse([]byte{0x69, 0x82})
+	}
+
+	response := fc.response
+	if response == nil {
+		response = []byte{0x90, 0x00}
+	}
+
+	encData, err := crypto.EncryptData(response, fc.encKey, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	meta = []byte{byte(len(encData) + 16), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	mac, err = crypto.CalculateMac(meta, encData, fc.macKey)
+	if err != nil {
+		return nil, err
+	}
+
+	data := append(mac, encData...)
+	if fc.tamper != nil {
+		fc.tamper(data)
+	}
+
+	return apdu.ParseResponse(append(data, 0x90, 0x00))
+}
+
+// pair answers both PAIR steps with fixed card challenge and salt, always using index 1.
+func (fc *fakeCard) pair(cmd *apdu.Command) (*apdu.Response, error) {
+	token := fc.pairingToken
+	if token == nil {
+		token = generatePairingToken(fc.pairingPass)
+	}
+
+	if cmd.P1 == P1PairingFirstStep {
+		cryptogram := sha256.Sum256(append(append([]byte{}, token...), cmd.Data...))
+		return apdu.ParseResponse(append(append(cryptogram[:], make([]byte, 32)...), 0x90, 0x00))
+	}
+
+	salt := bytes.Repeat([]byte{0x05}, 32)
+	pairingKey := sha256.Sum256(append(append([]byte{}, token...), salt...))
+	fc.pairingKey = pairingKey[:]
+
+	return apdu.ParseResponse(append(append([]byte{0x01}, salt...), 0x90, 0x00))
+}
+
+// init decrypts the INIT data and stores the pairing token following the PIN and the PUK.
+func (fc *fakeCard) init(cmd *apdu.Command) (*apdu.Response, error) {
+	pubKeyLength := int(cmd.Data[0])
+	clientKey, err := ethcrypto.UnmarshalPubkey(cmd.Data[1 : 1+pubKeyLength])
+	if err != nil {
+		return nil, err
+	}
+
+	secret := crypto.GenerateECDHSharedSecret(fc.key, clientKey)
+	iv := cmd.Data[1+pubKeyLength : 1+pubKeyLength+16]
+	data, err := crypto.DecryptData(cmd.Data[1+pubKeyLength+16:], secret, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	fc.pairingToken = data[pinLength+pukLength:]
+
+	return apdu.ParseResponse([]byte{0x90, 0x00})
+}
+
+func (fc *fakeCard) openSecureChannel(cmd *apdu.Command) (*apdu.Response, error) {
+	if fc.pairingKey == nil {
+		return apdu.ParseResponse([]byte{0x6A, 0x86})
+	}
+
+	clientKey, err := ethcrypto.UnmarshalPubkey(cmd.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	cardData := make([]byte, crypto.SessionSaltLength+crypto.SessionIVLength)
+	if _, err := rand.Read(cardData); err != nil {
+		return nil, err
+	}
+
+	secret := crypto.GenerateECDHSharedSecret(fc.key, clientKey)
+	fc.encKey, fc.macKey, _, err = crypto.DeriveSessionKeys(secret, fc.pairingKey, cardData)
+	if err != nil {
+		return nil, err
+	}
+
+	return apdu.ParseResponse(append(cardData, 0x90, 0x00))
+}
+
+func newTestSecureChannel(c types.Channel) *SecureChannel {
+	return &SecureChannel{
+		c:      c,
+		encKey: hexutils.HexToBytes("FDBCB1637597CF3F8F5E8263007D4E45F64C12D44066D4576EB1443D60AEF441"),
+		macKey: hexutils.HexToBytes("2FB70219E6635EE0958AB3F7A428BA87E8CD6E6F873A5725A55F25B102D0F1F7"),
+		iv:     hexutils.HexToBytes("627E64358FA9BDCDAD4442BD8006E0A5"),
+		open:   true,
+	}
+}
+
 func TestSecureChannel_Send(t *testing.T) {
 	c := &fakeChannel{}
 	sc := &SecureChannel{
@@ -39,3 +187,192 @@ func TestSecureChannel_Send(t *testing.T) {
 	expectedIV := "BA796BF8FAD1FD50407B87127B94F502"
 	assert.Equal(t, expectedIV, hexutils.BytesToHex(sc.iv))
 }
+
+func TestSecureChannel_ResetOnSecurityStatus(t *testing.T) {
+	c := keycardio.NewMockChannel().Respond(nil, SwSecurityConditionNotSatisfied)
+	sc := newTestSecureChannel(c)
+	sc.secret = []byte{0x01}
+
+	_, err := sc.Send(NewCommandGetStatus(P1GetStatusApplication))
+	assert.True(t, IsSecureChannelError(err))
+	assert.False(t, sc.open)
+	assert.Nil(t, sc.encKey)
+	assert.Nil(t, sc.macKey)
+	assert.Nil(t, sc.iv)
+	assert.Equal(t, []byte{0x01}, sc.Secret())
+}
+
+func TestSecureChannel_SendVerifiesResponseMAC(t *testing.T) {
+	sc := newTestSecureChannel(nil)
+	card := &fakeCard{
+		encKey:   sc.encKey,
+		macKey:   sc.macKey,
+		response: hexutils.HexToBytes("0102039000"),
+	}
+	sc.c = card
+
+	resp, err := sc.Send(NewCommandGetStatus(P1GetStatusApplication))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03}, resp.Data)
+	assert.Equal(t, uint16(apdu.SwOK), resp.Sw)
+
+	card.tamper = func(data []byte) { data[len(data)-1] ^= 0x01 }
+	_, err = sc.Send(NewCommandGetStatus(P1GetStatusApplication))
+	assert.Equal(t, ErrInvalidResponseMAC, err)
+	assert.False(t, sc.open)
+
+	// responses without data after the MAC
+	for _, data := range [][]byte{{0x01, 0x02}, make([]byte, 16)} {
+		sc = newTestSecureChannel(keycardio.NewMockChannel().Respond(data, apdu.SwOK))
+		_, err = sc.Send(NewCommandGetStatus(P1GetStatusApplication))
+		assert.Equal(t, ErrInvalidResponseMAC, err)
+	}
+}
+
+func TestSecureChannel_IsOpen(t *testing.T) {
+	sc := NewSecureChannel(keycardio.NewMockChannel().Respond(nil, SwSecurityConditionNotSatisfied))
+	assert.False(t, sc.IsOpen())
+
+	sc.Init(make([]byte, 16), make([]byte, 32), make([]byte, 32))
+	assert.True(t, sc.IsOpen())
+
+	_, err := sc.Send(NewCommandGetStatus(P1GetStatusApplication))
+	assert.Error(t, err)
+	assert.False(t, sc.IsOpen())
+
+	sc.Init(make([]byte, 16), make([]byte, 32), make([]byte, 32))
+	sc.Reset()
+	assert.False(t, sc.IsOpen())
+}
+
+func TestSecureChannel_Exhausted(t *testing.T) {
+	sc := newTestSecureChannel(nil)
+	sc.c = &fakeCard{encKey: sc.encKey, macKey: sc.macKey}
+
+	for i := 0; i < 10; i++ {
+		_, err := sc.Send(NewCommandGetStatus(P1GetStatusApplication))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, uint64(10), sc.commands)
+
+	// skip to the end of the session
+	sc.commands = MaxSecureChannelCommands - 1
+	_, err := sc.Send(NewCommandGetStatus(P1GetStatusApplication))
+	require.NoError(t, err)
+
+	_, err = sc.Send(NewCommandGetStatus(P1GetStatusApplication))
+	assert.Equal(t, ErrSecureChannelExhausted, err)
+	assert.True(t, IsSecureChannelError(err))
+	assert.False(t, sc.IsOpen())
+
+	sc.Init(make([]byte, 16), make([]byte, 32), make([]byte, 32))
+	assert.Equal(t, uint64(0), sc.commands)
+}
+
+func TestSecureChannel_OneShotEncrypt(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	sc := NewSecureChannel(nil)
+	require.NoError(t, sc.GenerateSecret(ethcrypto.FromECDSAPub(&cardKey.PublicKey)))
+	secrets, err := NewSecrets("123456", "123456789012", "KeycardTest")
+	require.NoError(t, err)
+
+	data, err := sc.OneShotEncrypt(secrets)
+	require.NoError(t, err)
+	require.Len(t, data, initDataLength)
+	assert.Equal(t, sc.RawPublicKey(), data[1:66])
+
+	// the card decrypts the data and removes the padding
+	block, err := aes.NewCipher(sc.Secret())
+	require.NoError(t, err)
+	plain := make([]byte, 64)
+	cipher.NewCBCDecrypter(block, data[66:82]).CryptBlocks(plain, data[82:])
+
+	expected := append([]byte("123456123456789012"), secrets.PairingToken()...)
+	assert.Equal(t, expected, plain[:50])
+	assert.Equal(t, byte(0x80), plain[50])
+	assert.Equal(t, make([]byte, 13), plain[51:])
+
+	_, err = sc.OneShotEncrypt(&Secrets{pin: "123456", puk: "123456789012", pairingToken: make([]byte, 48)})
+	assert.Equal(t, ErrInvalidInitDataLength, err)
+}
+
+func TestSecureChannel_SendPlain(t *testing.T) {
+	c := keycardio.NewMockChannel().Respond([]byte{0x01}, apdu.SwOK)
+	sc := newTestSecureChannel(c)
+	iv := append([]byte{}, sc.iv...)
+
+	cmd := NewCommandGetStatus(P1GetStatusApplication)
+	data := append([]byte{}, cmd.Data...)
+	resp, err := sc.SendPlain(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, resp.Data)
+	assert.Equal(t, data, c.Sent[0].Data)
+	assert.Equal(t, iv, sc.iv)
+	assert.True(t, sc.IsOpen())
+}
+
+func TestSecureChannel_SendInvalidResponseLength(t *testing.T) {
+	for _, n := range []int{17, 31, 33} {
+		sc := newTestSecureChannel(keycardio.NewMockChannel().Respond(make([]byte, n), apdu.SwOK))
+		_, err := sc.Send(NewCommandGetStatus(P1GetStatusApplication))
+		assert.Equal(t, ErrInvalidResponseMAC, err, n)
+		assert.False(t, sc.IsOpen(), n)
+	}
+}
+
+func TestSecureChannel_Close(t *testing.T) {
+	sc := newTestSecureChannel(nil)
+	sc.secret = []byte{0x01, 0x02}
+	encKey, macKey, iv, secret := sc.encKey, sc.macKey, sc.iv, sc.secret
+
+	sc.Close()
+	assert.False(t, sc.open)
+	assert.Nil(t, sc.Secret())
+	assert.Nil(t, sc.PublicKey())
+	assert.Equal(t, make([]byte, 32), encKey)
+	assert.Equal(t, make([]byte, 32), macKey)
+	assert.Equal(t, make([]byte, 16), iv)
+	assert.Equal(t, make([]byte, 2), secret)
+}
+
+func TestSecureChannel_GenerateSecretInvalidKey(t *testing.T) {
+	cardKey, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	pubKey := ethcrypto.FromECDSAPub(&cardKey.PublicKey)
+
+	sc := NewSecureChannel(nil)
+	require.NoError(t, sc.GenerateSecret(pubKey))
+	assert.Len(t, sc.Secret(), 32)
+
+	offCurve := append([]byte{}, pubKey...)
+	offCurve[64] ^= 0x01
+	assert.Equal(t, ErrInvalidCardPublicKey, sc.GenerateSecret(offCurve))
+	assert.Nil(t, sc.Secret())
+	assert.Nil(t, sc.PublicKey())
+
+	assert.Equal(t, ErrInvalidCardPublicKey, sc.GenerateSecret(pubKey[1:]))
+	assert.Equal(t, ErrInvalidCardPublicKey, sc.GenerateSecret(nil))
+}
+
+func BenchmarkSecureChannel_Sign(b *testing.B) {
+	hash := make([]byte, 32)
+	resp, _ := signResponse(b, hash)
+
+	sc := newTestSecureChannel(nil)
+	sc.c = &fakeCard{
+		encKey:   sc.encKey,
+		macKey:   sc.macKey,
+		response: append(resp, 0x90, 0x00),
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cmd, _ := NewCommandSign(hash, P1SignCurrentKey, "")
+		if _, err := sc.Send(cmd); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
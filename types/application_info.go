@@ -1,7 +1,10 @@
 package types
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
+	"fmt"
 
 	"github.com/status-im/keycard-go/apdu"
 )
@@ -15,6 +18,10 @@ const (
 	TagApplicationStatusTemplate    uint8 = 0xA3
 	TagApplicationInfoTemplate      uint8 = 0xA4
 	TagApplicationInfoCapabilities  uint8 = 0x8D
+	// TagFCITemplate is the ISO 7816-4 file control information template some card OS
+	// wrap the SELECT response in, and TagFCIProprietary its proprietary data template.
+	TagFCITemplate    uint8 = 0x6F
+	TagFCIProprietary uint8 = 0xA5
 )
 
 const (
@@ -22,14 +29,26 @@ const (
 	CapabilityKeyManagement
 	CapabilityCredentialsManagement
 	CapabilityNDEF
+	CapabilityFactoryReset
 
+	// CapabilityAll are the capabilities of cards not reporting them.
 	CapabilityAll = CapabilitySecureChannel |
 		CapabilityKeyManagement |
 		CapabilityCredentialsManagement |
 		CapabilityNDEF
 )
 
+// AppletVariant identifies which applet of the Keycard family answered the SELECT command.
+type AppletVariant uint8
+
+const (
+	VariantKeycard AppletVariant = iota
+	VariantCash
+	VariantNDEF
+)
+
 type ApplicationInfo struct {
+	Variant                AppletVariant
 	Installed              bool
 	Initialized            bool
 	InstanceUID            []byte
@@ -42,6 +61,39 @@ type ApplicationInfo struct {
 	Capabilities Capability
 }
 
+// Version is the applet version reported in the application info.
+type Version struct {
+	Major int
+	Minor int
+}
+
+// AtLeast returns true if v is equal or greater than major.minor.
+func (v Version) AtLeast(major, minor int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+
+	return v.Minor >= minor
+}
+
+// String returns the version formatted as major.minor.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// ParsedVersion decodes the raw version field, where the high byte is the major version
+// and the low byte the minor version. It returns a zero Version if the field is missing.
+func (a *ApplicationInfo) ParsedVersion() Version {
+	if len(a.Version) != 2 {
+		return Version{}
+	}
+
+	return Version{
+		Major: int(a.Version[0]),
+		Minor: int(a.Version[1]),
+	}
+}
+
 func (a *ApplicationInfo) HasCapability(c Capability) bool {
 	return a.Capabilities&c == c
 }
@@ -62,28 +114,125 @@ func (a *ApplicationInfo) HasNDEFCapability() bool {
 	return a.HasCapability(CapabilityNDEF)
 }
 
-func ParseApplicationInfo(data []byte) (*ApplicationInfo, error) {
-	info := &ApplicationInfo{
-		Installed: true,
+func (a *ApplicationInfo) HasFactoryResetCapability() bool {
+	return a.HasCapability(CapabilityFactoryReset)
+}
+
+// HasAvailablePairingSlots returns false if the card reported that all its pairing slots are in use.
+// It returns true if the number of available slots is unknown.
+func (a *ApplicationInfo) HasAvailablePairingSlots() bool {
+	return len(a.AvailableSlots) == 0 || a.AvailableSlots[0] > 0
+}
+
+// Fingerprint returns an identifier of the card: the InstanceUID once initialized.
+// Pre-initialized cards don't have an instance UID yet and only expose their secure channel
+// public key, so the first 16 bytes of its sha256 are used instead. That fingerprint is stable
+// across selects but changes once the card is initialized. It returns nil if both are missing.
+func (a *ApplicationInfo) Fingerprint() []byte {
+	if len(a.InstanceUID) > 0 {
+		return a.InstanceUID
 	}
 
-	if data[0] == TagSelectResponsePreInitialized {
-		info.SecureChannelPublicKey = data[2:]
-		info.Capabilities = CapabilityCredentialsManagement
+	if len(a.SecureChannelPublicKey) == 0 {
+		return nil
+	}
 
-		if len(info.SecureChannelPublicKey) > 0 {
-			info.Capabilities = info.Capabilities | CapabilitySecureChannel
+	h := sha256.Sum256(a.SecureChannelPublicKey)
+	return h[:16]
+}
+
+// ParseApplicationInfo parses the SELECT response of the Keycard or the Cash applet.
+// Pre-initialized cards answer with their secure channel public key only, while initialized
+// cards and the Cash applet answer with an application info template, told apart by its content.
+// Responses wrapped in an FCI template by the card OS are unwrapped first.
+func ParseApplicationInfo(data []byte) (*ApplicationInfo, error) {
+	if len(data) == 0 {
+		return nil, ErrWrongApplicationInfoTemplate
+	}
+
+	switch data[0] {
+	case TagSelectResponsePreInitialized:
+		return parsePreInitializedApplicationInfo(data)
+	case TagFCITemplate:
+		return parseFCIApplicationInfo(data)
+	case TagApplicationInfoTemplate:
+		if _, err := apdu.FindTag(data, apdu.Tag{TagApplicationInfoTemplate}, apdu.Tag{0x8F}); apdu.IsTagNotFound(err) {
+			return parseCashApplicationInfo(data)
+		} else if err != nil {
+			return nil, err
 		}
 
-		return info, nil
+		return parseKeycardApplicationInfo(data)
+	default:
+		return nil, ErrWrongApplicationInfoTemplate
+	}
+}
+
+// parseFCIApplicationInfo parses the application info found in an FCI template: an application
+// info template directly inside it, or a pre-initialized response in its proprietary data template.
+func parseFCIApplicationInfo(data []byte) (*ApplicationInfo, error) {
+	inner := new(bytes.Buffer)
+
+	value, err := apdu.FindTag(data, apdu.Tag{TagFCITemplate}, apdu.Tag{TagApplicationInfoTemplate})
+	if err == nil {
+		apdu.WriteTLV(inner, apdu.Tag{TagApplicationInfoTemplate}, value)
+		return ParseApplicationInfo(inner.Bytes())
+	} else if !apdu.IsTagNotFound(err) {
+		return nil, err
+	}
+
+	value, err = apdu.FindTag(data, apdu.Tag{TagFCITemplate}, apdu.Tag{TagFCIProprietary}, apdu.Tag{TagSelectResponsePreInitialized})
+	if apdu.IsTagNotFound(err) {
+		return nil, ErrWrongApplicationInfoTemplate
+	} else if err != nil {
+		return nil, err
 	}
 
-	info.Initialized = true
+	apdu.WriteTLV(inner, apdu.Tag{TagSelectResponsePreInitialized}, value)
+
+	return ParseApplicationInfo(inner.Bytes())
+}
 
-	if data[0] != TagApplicationInfoTemplate {
+func parsePreInitializedApplicationInfo(data []byte) (*ApplicationInfo, error) {
+	if len(data) < 2 {
 		return nil, ErrWrongApplicationInfoTemplate
 	}
 
+	info := &ApplicationInfo{
+		Installed:              true,
+		SecureChannelPublicKey: data[2:],
+		Capabilities:           CapabilityCredentialsManagement,
+	}
+
+	if len(info.SecureChannelPublicKey) > 0 {
+		info.Capabilities = info.Capabilities | CapabilitySecureChannel
+	}
+
+	return info, nil
+}
+
+// parseCashApplicationInfo maps the Cash applet template, which has no instance UID,
+// no secure channel and no key management.
+func parseCashApplicationInfo(data []byte) (*ApplicationInfo, error) {
+	cashInfo, err := ParseCashApplicationInfo(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ApplicationInfo{
+		Variant:     VariantCash,
+		Installed:   true,
+		Initialized: true,
+		Version:     cashInfo.Version,
+	}, nil
+}
+
+func parseKeycardApplicationInfo(data []byte) (*ApplicationInfo, error) {
+	info := &ApplicationInfo{
+		Installed:   true,
+		Initialized: true,
+	}
+
 	instanceUID, err := apdu.FindTag(data, apdu.Tag{TagApplicationInfoTemplate}, apdu.Tag{0x8F})
 	if err != nil {
 		return nil, err
@@ -109,9 +258,14 @@ func ParseApplicationInfo(data []byte) (*ApplicationInfo, error) {
 		return nil, err
 	}
 
+	// cards not reporting capabilities support all of them
 	capabilities := CapabilityAll
-	capabilitiesBytes, err := apdu.FindTag(data, apdu.Tag{TagApplicationInfoCapabilities})
-	if err == nil && len(capabilitiesBytes) > 0 {
+	capabilitiesBytes, err := apdu.FindTag(data, apdu.Tag{TagApplicationInfoTemplate}, apdu.Tag{TagApplicationInfoCapabilities})
+	if err != nil && !apdu.IsTagNotFound(err) {
+		return nil, err
+	}
+
+	if len(capabilitiesBytes) > 0 {
 		capabilities = Capability(capabilitiesBytes[0])
 	}
 
@@ -0,0 +1,165 @@
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplicationInfo_ParsedVersion(t *testing.T) {
+	info := &ApplicationInfo{Version: []byte{0x03, 0x01}}
+	v := info.ParsedVersion()
+	assert.Equal(t, Version{Major: 3, Minor: 1}, v)
+	assert.Equal(t, "3.1", v.String())
+	assert.True(t, v.AtLeast(3, 0))
+	assert.True(t, v.AtLeast(3, 1))
+	assert.True(t, v.AtLeast(2, 9))
+	assert.False(t, v.AtLeast(3, 2))
+	assert.False(t, v.AtLeast(4, 0))
+
+	info = &ApplicationInfo{}
+	assert.Equal(t, Version{}, info.ParsedVersion())
+}
+
+func applicationInfoResponse(capabilities []byte) []byte {
+	tpl := new(bytes.Buffer)
+	writeTag(tpl, 0x8F, bytes.Repeat([]byte{0x01}, 16))
+	writeTag(tpl, 0x80, append([]byte{0x04}, bytes.Repeat([]byte{0x02}, 64)...))
+	writeTag(tpl, 0x02, []byte{0x03, 0x01})
+	writeTag(tpl, 0x02, []byte{0x04})
+	tpl.Write([]byte{0x8E, 0x00})
+	writeTag(tpl, TagApplicationInfoCapabilities, capabilities)
+
+	buf := new(bytes.Buffer)
+	writeTag(buf, TagApplicationInfoTemplate, tpl.Bytes())
+
+	return buf.Bytes()
+}
+
+func TestParseApplicationInfo(t *testing.T) {
+	info, err := ParseApplicationInfo(applicationInfoResponse(nil))
+	require.NoError(t, err)
+	assert.True(t, info.Installed)
+	assert.True(t, info.Initialized)
+	assert.Equal(t, bytes.Repeat([]byte{0x01}, 16), info.InstanceUID)
+	assert.Len(t, info.SecureChannelPublicKey, 65)
+	assert.Equal(t, []byte{0x03, 0x01}, info.Version)
+	assert.Equal(t, []byte{0x04}, info.AvailableSlots)
+	assert.True(t, info.HasAvailablePairingSlots())
+	assert.Empty(t, info.KeyUID)
+	assert.Equal(t, CapabilityAll, info.Capabilities)
+
+	info, err = ParseApplicationInfo(applicationInfoResponse([]byte{byte(CapabilitySecureChannel | CapabilityNDEF)}))
+	require.NoError(t, err)
+	assert.True(t, info.HasSecureChannelCapability())
+	assert.True(t, info.HasNDEFCapability())
+	assert.False(t, info.HasKeyManagementCapability())
+	assert.False(t, info.HasCredentialsManagementCapability())
+}
+
+func TestApplicationInfo_HasAvailablePairingSlots(t *testing.T) {
+	assert.True(t, (&ApplicationInfo{}).HasAvailablePairingSlots())
+	assert.True(t, (&ApplicationInfo{AvailableSlots: []byte{0x01}}).HasAvailablePairingSlots())
+	assert.False(t, (&ApplicationInfo{AvailableSlots: []byte{0x00}}).HasAvailablePairingSlots())
+}
+
+func TestApplicationInfo_Fingerprint(t *testing.T) {
+	preInit, err := ParseApplicationInfo(append([]byte{TagSelectResponsePreInitialized, 0x04}, 0x04, 0x01, 0x02, 0x03))
+	require.NoError(t, err)
+	assert.Nil(t, preInit.InstanceUID)
+
+	fingerprint := preInit.Fingerprint()
+	assert.Len(t, fingerprint, 16)
+	assert.Equal(t, fingerprint, preInit.Fingerprint())
+
+	other := &ApplicationInfo{SecureChannelPublicKey: []byte{0x04, 0x01, 0x02, 0x04}}
+	assert.NotEqual(t, fingerprint, other.Fingerprint())
+
+	uid := []byte{0x01, 0x02}
+	assert.Equal(t, uid, (&ApplicationInfo{InstanceUID: uid, SecureChannelPublicKey: []byte{0x04}}).Fingerprint())
+	assert.Nil(t, (&ApplicationInfo{}).Fingerprint())
+}
+
+func TestParseApplicationInfo_FCI(t *testing.T) {
+	aid := []byte{0xA0, 0x00, 0x00, 0x08, 0x04, 0x00, 0x01, 0x01, 0x01}
+	appInfo := applicationInfoResponse(nil)
+
+	fci := new(bytes.Buffer)
+	writeTag(fci, 0x84, aid)
+	fci.Write(appInfo)
+	wrapped := new(bytes.Buffer)
+	writeTag(wrapped, TagFCITemplate, fci.Bytes())
+
+	info, err := ParseApplicationInfo(wrapped.Bytes())
+	require.NoError(t, err)
+	assert.True(t, info.Initialized)
+	assert.Equal(t, bytes.Repeat([]byte{0x01}, 16), info.InstanceUID)
+
+	// inside the proprietary template, with a pre-initialized card
+	proprietary := new(bytes.Buffer)
+	writeTag(proprietary, TagFCIProprietary, []byte{TagSelectResponsePreInitialized, 0x02, 0x04, 0x05})
+	fci.Reset()
+	writeTag(fci, 0x84, aid)
+	fci.Write(proprietary.Bytes())
+	wrapped.Reset()
+	writeTag(wrapped, TagFCITemplate, fci.Bytes())
+
+	info, err = ParseApplicationInfo(wrapped.Bytes())
+	require.NoError(t, err)
+	assert.False(t, info.Initialized)
+	assert.Equal(t, []byte{0x04, 0x05}, info.SecureChannelPublicKey)
+
+	// an FCI template without application info
+	wrapped.Reset()
+	writeTag(wrapped, TagFCITemplate, []byte{0x84, 0x01, 0xA0})
+	_, err = ParseApplicationInfo(wrapped.Bytes())
+	assert.Equal(t, ErrWrongApplicationInfoTemplate, err)
+
+	// a pre-initialized response directly in the FCI template
+	wrapped.Reset()
+	writeTag(wrapped, TagFCITemplate, []byte{TagSelectResponsePreInitialized, 0x02, 0x04, 0x05})
+	_, err = ParseApplicationInfo(wrapped.Bytes())
+	assert.Equal(t, ErrWrongApplicationInfoTemplate, err)
+
+	// an application info template in the proprietary template
+	proprietary.Reset()
+	writeTag(proprietary, TagFCIProprietary, appInfo)
+	wrapped.Reset()
+	writeTag(wrapped, TagFCITemplate, proprietary.Bytes())
+	_, err = ParseApplicationInfo(wrapped.Bytes())
+	assert.Equal(t, ErrWrongApplicationInfoTemplate, err)
+}
+
+func TestParseApplicationInfo_Variants(t *testing.T) {
+	info, err := ParseApplicationInfo(applicationInfoResponse(nil))
+	require.NoError(t, err)
+	assert.Equal(t, VariantKeycard, info.Variant)
+
+	info, err = ParseApplicationInfo([]byte{TagSelectResponsePreInitialized, 0x00})
+	require.NoError(t, err)
+	assert.Equal(t, VariantKeycard, info.Variant)
+	assert.False(t, info.Initialized)
+	assert.False(t, info.HasSecureChannelCapability())
+
+	tpl := new(bytes.Buffer)
+	writeTag(tpl, 0x80, append([]byte{0x04}, bytes.Repeat([]byte{0x02}, 64)...))
+	writeTag(tpl, 0x82, []byte{0x01})
+	writeTag(tpl, 0x02, []byte{0x01, 0x00})
+	cash := new(bytes.Buffer)
+	writeTag(cash, TagApplicationInfoTemplate, tpl.Bytes())
+
+	info, err = ParseApplicationInfo(cash.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, VariantCash, info.Variant)
+	assert.True(t, info.Initialized)
+	assert.Equal(t, []byte{0x01, 0x00}, info.Version)
+	assert.False(t, info.HasSecureChannelCapability())
+	assert.False(t, info.HasKeyManagementCapability())
+
+	for _, data := range [][]byte{nil, {TagSelectResponsePreInitialized}, {0x6F, 0x00}} {
+		_, err = ParseApplicationInfo(data)
+		assert.Equal(t, ErrWrongApplicationInfoTemplate, err)
+	}
+}
@@ -12,6 +12,8 @@ const hardenedStart = 0x80000000 // 2^31
 
 var ErrApplicationStatusTemplateNotFound = errors.New("application status template not found")
 
+// ApplicationStatus is the GET STATUS response: the retry counters and key status, or the
+// current key path. The applet doesn't report the pinless path set with SET PINLESS PATH.
 type ApplicationStatus struct {
 	PinRetryCount  int
 	PUKRetryCount  int
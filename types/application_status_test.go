@@ -0,0 +1,33 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseApplicationStatus(t *testing.T) {
+	status, err := ParseApplicationStatus(hexutils.HexToBytes("A3 09 02 01 03 02 01 05 01 01 FF"))
+	require.NoError(t, err)
+	assert.Equal(t, &ApplicationStatus{
+		PinRetryCount:  3,
+		PUKRetryCount:  5,
+		KeyInitialized: true,
+	}, status)
+
+	status, err = ParseApplicationStatus(hexutils.HexToBytes("A3 09 02 01 00 02 01 00 01 01 00"))
+	require.NoError(t, err)
+	assert.Equal(t, &ApplicationStatus{}, status)
+}
+
+func TestParseApplicationStatus_KeyPath(t *testing.T) {
+	status, err := ParseApplicationStatus(hexutils.HexToBytes("8000002C 8000003C 80000000 00000000 00000001"))
+	require.NoError(t, err)
+	assert.Equal(t, "m/44'/60'/0'/0/1", status.Path)
+
+	status, err = ParseApplicationStatus([]byte{})
+	require.NoError(t, err)
+	assert.Equal(t, "m", status.Path)
+}
@@ -0,0 +1,42 @@
+package types
+
+// CardReport summarizes the application info returned by the applet on select.
+type CardReport struct {
+	Installed   bool
+	Initialized bool
+	Version     Version
+	// Capabilities are the capabilities reported by the card, or CapabilityAll for older versions.
+	Capabilities Capability
+	InstanceUID  []byte
+	// KeyUID is empty if the card doesn't have a key.
+	KeyUID []byte
+	// FreePairingSlots is -1 if the card didn't report it, like pre-initialized cards.
+	FreePairingSlots int
+	// Fingerprint identifies the card, see ApplicationInfo.Fingerprint.
+	Fingerprint []byte
+}
+
+// NewCardReport returns the report of info.
+func NewCardReport(info *ApplicationInfo) *CardReport {
+	report := &CardReport{
+		Installed:        info.Installed,
+		Initialized:      info.Initialized,
+		Version:          info.ParsedVersion(),
+		Capabilities:     info.Capabilities,
+		InstanceUID:      info.InstanceUID,
+		KeyUID:           info.KeyUID,
+		FreePairingSlots: -1,
+		Fingerprint:      info.Fingerprint(),
+	}
+
+	if len(info.AvailableSlots) > 0 {
+		report.FreePairingSlots = int(info.AvailableSlots[0])
+	}
+
+	return report
+}
+
+// HasKey returns true if the card has a master key.
+func (r *CardReport) HasKey() bool {
+	return len(r.KeyUID) > 0
+}
@@ -12,7 +12,7 @@ type CashApplicationInfo struct {
 func ParseCashApplicationInfo(data []byte) (*CashApplicationInfo, error) {
 	info := &CashApplicationInfo{}
 
-	if data[0] != TagApplicationInfoTemplate {
+	if len(data) == 0 || data[0] != TagApplicationInfoTemplate {
 		return nil, ErrWrongApplicationInfoTemplate
 	}
 
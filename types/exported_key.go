@@ -12,25 +12,42 @@ var (
 	TagExportKeyPublic   = uint8(0x81)
 )
 
+// ParseExportKeyResponse parses the EXPORT KEY response and returns the private and public keys.
 func ParseExportKeyResponse(data []byte) ([]byte, []byte, error) {
-	tpl, err := apdu.FindTag(data, apdu.Tag{0xA1})
+	keyPair, err := ParseExportedKeyPair(data)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	pubKey := tryFindTag(tpl, apdu.Tag{0x80})
-	privKey := tryFindTag(tpl, apdu.Tag{0x81})
+	return keyPair.PrivateKey, keyPair.PublicKey, nil
+}
+
+// ParseExportedKeyPair parses the EXPORT KEY response into a KeyPair.
+// Fields not returned by the card are left nil.
+func ParseExportedKeyPair(data []byte) (*KeyPair, error) {
+	tpl, err := apdu.FindTag(data, apdu.Tag{TagKeyPairTemplate})
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey := tryFindTag(tpl, apdu.Tag{TagKeyPairPublicKey})
+	privKey := tryFindTag(tpl, apdu.Tag{TagKeyPairPrivateKey})
+	chainCode := tryFindTag(tpl, apdu.Tag{TagKeyPairChainCode})
 
 	if len(pubKey) == 0 && len(privKey) > 0 {
 		ecdsaKey, err := ethcrypto.HexToECDSA(fmt.Sprintf("%x", privKey))
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
 		pubKey = ethcrypto.FromECDSAPub(&ecdsaKey.PublicKey)
 	}
 
-	return privKey, pubKey, nil
+	return &KeyPair{
+		PrivateKey: nonEmpty(privKey),
+		PublicKey:  nonEmpty(pubKey),
+		ChainCode:  nonEmpty(chainCode),
+	}, nil
 }
 
 func tryFindTag(tpl []byte, tags ...apdu.Tag) []byte {
@@ -41,3 +58,11 @@ func tryFindTag(tpl []byte, tags ...apdu.Tag) []byte {
 
 	return data
 }
+
+func nonEmpty(data []byte) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+
+	return data
+}
@@ -0,0 +1,28 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExportedKeyPair(t *testing.T) {
+	kp, err := ParseExportedKeyPair(hexutils.HexToBytes("A1 05 80 03 04 05 06"))
+	require.NoError(t, err)
+	assert.Equal(t, &KeyPair{PublicKey: hexutils.HexToBytes("040506")}, kp)
+
+	kp, err = ParseExportedKeyPair(hexutils.HexToBytes("A1 09 80 03 04 05 06 82 02 AA BB"))
+	require.NoError(t, err)
+	assert.Nil(t, kp.PrivateKey)
+	assert.Equal(t, hexutils.HexToBytes("040506"), kp.PublicKey)
+	assert.Equal(t, hexutils.HexToBytes("AABB"), kp.ChainCode)
+
+	privKey := hexutils.HexToBytes("0000000000000000000000000000000000000000000000000000000000000001")
+	data := (&KeyPair{PrivateKey: privKey}).Serialize()
+	kp, err = ParseExportedKeyPair(data)
+	require.NoError(t, err)
+	assert.Equal(t, privKey, kp.PrivateKey)
+	assert.Equal(t, "0479BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8", hexutils.BytesToHex(kp.PublicKey))
+}
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/status-im/keycard-go/apdu"
+)
+
+var (
+	TagIdentityTemplate    = uint8(0xA0)
+	TagIdentityCertificate = uint8(0x8A)
+)
+
+// identityCertificateLength is the length of the compressed card public key
+// followed by the 65 bytes recoverable CA signature.
+const identityCertificateLength = 33 + 65
+
+var ErrInvalidIdentityCertificate = errors.New("invalid identity certificate")
+var ErrInvalidIdentitySignature = errors.New("identity signature does not match the card public key")
+var ErrInvalidIdentityCA = errors.New("identity certificate not signed by the CA")
+
+// Identity is the response to the IDENT command. The card signs the challenge with
+// its identity key, whose public key is certified by the manufacturer CA.
+type Identity struct {
+	Challenge []byte
+	// CardPublicKey is the compressed public key of the card identity key.
+	CardPublicKey []byte
+	// CertificateSignature is the recoverable CA signature of the sha256 of CardPublicKey.
+	CertificateSignature []byte
+	// Signature is the signature of Challenge, as R || S with S in the lower half of the curve order.
+	Signature []byte
+}
+
+// ParseIdentity parses the IDENT response to challenge.
+func ParseIdentity(challenge, data []byte) (*Identity, error) {
+	cert, err := apdu.FindTag(data, apdu.Tag{TagIdentityTemplate}, apdu.Tag{TagIdentityCertificate})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cert) != identityCertificateLength {
+		return nil, ErrInvalidIdentityCertificate
+	}
+
+	r, err := apdu.FindTagN(data, 0, apdu.Tag{TagIdentityTemplate}, apdu.Tag{0x30}, apdu.Tag{0x02})
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := apdu.FindTagN(data, 1, apdu.Tag{TagIdentityTemplate}, apdu.Tag{0x30}, apdu.Tag{0x02})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r) > 32 {
+		r = r[len(r)-32:]
+	}
+
+	if len(s) > 32 {
+		s = s[len(s)-32:]
+	}
+
+	return &Identity{
+		Challenge:            challenge,
+		CardPublicKey:        cert[:33],
+		CertificateSignature: cert[33:],
+		Signature:            append(leftPad32(r), normalizeS(s)...),
+	}, nil
+}
+
+// normalizeS returns the lower of s and N - s, padded to 32 bytes. The card doesn't normalize
+// its ECDSA signatures, while crypto.VerifySignature rejects those with S in the upper half
+// of the curve order. Both values are valid signatures of the same hash.
+func normalizeS(s []byte) []byte {
+	n := crypto.S256().Params().N
+	v := new(big.Int).SetBytes(s)
+	if v.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		v.Sub(n, v)
+	}
+
+	return leftPad32(v.Bytes())
+}
+
+// Verify checks that the challenge is signed by the card identity key
+// and that the key is certified by the CA with public key caPubKey.
+// caPubKey can be either compressed or uncompressed.
+func (i *Identity) Verify(caPubKey []byte) error {
+	if !crypto.VerifySignature(i.CardPublicKey, i.Challenge, i.Signature) {
+		return ErrInvalidIdentitySignature
+	}
+
+	hash := sha256.Sum256(i.CardPublicKey)
+	if !crypto.VerifySignature(caPubKey, hash[:], i.CertificateSignature[:64]) {
+		return ErrInvalidIdentityCA
+	}
+
+	return nil
+}
+
+// CAPublicKey recovers the compressed public key of the CA that signed the certificate.
+func (i *Identity) CAPublicKey() ([]byte, error) {
+	hash := sha256.Sum256(i.CardPublicKey)
+	pubKey, err := crypto.SigToPub(hash[:], i.CertificateSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.CompressPubkey(pubKey), nil
+}
@@ -0,0 +1,92 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// identityResponse returns an IDENT response to challenge and the CA public key. With highS,
+// the card signature has S in the upper half of the curve order, like cards can return.
+func identityResponse(t *testing.T, challenge []byte, highS bool) ([]byte, []byte) {
+	caKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	cardKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	cardPubKey := crypto.CompressPubkey(&cardKey.PublicKey)
+	hash := sha256.Sum256(cardPubKey)
+	certSig, err := crypto.Sign(hash[:], caKey)
+	require.NoError(t, err)
+
+	sig, err := crypto.Sign(challenge, cardKey)
+	require.NoError(t, err)
+
+	sigS := sig[32:64]
+	if highS {
+		sigS = new(big.Int).Sub(crypto.S256().Params().N, new(big.Int).SetBytes(sigS)).Bytes()
+		// DER integers with the high bit set need a leading zero
+		sigS = append([]byte{0x00}, sigS...)
+	}
+
+	der := new(bytes.Buffer)
+	writeTag(der, 0x02, sig[:32])
+	writeTag(der, 0x02, sigS)
+
+	tpl := new(bytes.Buffer)
+	writeTag(tpl, TagIdentityCertificate, append(cardPubKey, certSig...))
+	writeTag(tpl, 0x30, der.Bytes())
+
+	buf := new(bytes.Buffer)
+	writeTag(buf, TagIdentityTemplate, tpl.Bytes())
+
+	return buf.Bytes(), crypto.CompressPubkey(&caKey.PublicKey)
+}
+
+func TestIdentity_Verify(t *testing.T) {
+	challenge := bytes.Repeat([]byte{0x01}, 32)
+	data, caPubKey := identityResponse(t, challenge, false)
+
+	identity, err := ParseIdentity(challenge, data)
+	require.NoError(t, err)
+	require.NoError(t, identity.Verify(caPubKey))
+
+	recovered, err := identity.CAPublicKey()
+	require.NoError(t, err)
+	assert.Equal(t, caPubKey, recovered)
+
+	_, otherCA := identityResponse(t, challenge, false)
+	assert.Equal(t, ErrInvalidIdentityCA, identity.Verify(otherCA))
+
+	identity, err = ParseIdentity(bytes.Repeat([]byte{0x02}, 32), data)
+	require.NoError(t, err)
+	assert.Equal(t, ErrInvalidIdentitySignature, identity.Verify(caPubKey))
+}
+
+func TestIdentity_VerifyHighS(t *testing.T) {
+	challenge := bytes.Repeat([]byte{0x01}, 32)
+	data, caPubKey := identityResponse(t, challenge, true)
+
+	identity, err := ParseIdentity(challenge, data)
+	require.NoError(t, err)
+	require.NoError(t, identity.Verify(caPubKey))
+
+	halfN := new(big.Int).Rsh(crypto.S256().Params().N, 1)
+	assert.True(t, new(big.Int).SetBytes(identity.Signature[32:]).Cmp(halfN) <= 0)
+	assert.Len(t, identity.Signature, 64)
+}
+
+func TestParseIdentity_InvalidCertificate(t *testing.T) {
+	buf := new(bytes.Buffer)
+	writeTag(buf, TagIdentityCertificate, []byte{0x01, 0x02})
+	tpl := new(bytes.Buffer)
+	writeTag(tpl, TagIdentityTemplate, buf.Bytes())
+
+	_, err := ParseIdentity(nil, tpl.Bytes())
+	assert.Equal(t, ErrInvalidIdentityCertificate, err)
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"bytes"
+
+	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/ethutils"
+)
+
+var (
+	TagKeyPairTemplate   = uint8(0xA1)
+	TagKeyPairPublicKey  = uint8(0x80)
+	TagKeyPairPrivateKey = uint8(0x81)
+	TagKeyPairChainCode  = uint8(0x82)
+)
+
+// KeyPair contains the components of a key as loaded to or exported from the card.
+// PublicKey and ChainCode are optional.
+type KeyPair struct {
+	PrivateKey []byte
+	PublicKey  []byte
+	ChainCode  []byte
+}
+
+// IsExtended returns true if the key pair has a chain code.
+func (kp *KeyPair) IsExtended() bool {
+	return len(kp.ChainCode) > 0
+}
+
+// CompressedPublicKey returns the 33 bytes compressed form of PublicKey.
+func (kp *KeyPair) CompressedPublicKey() ([]byte, error) {
+	return ethutils.CompressPublicKey(kp.PublicKey)
+}
+
+// Serialize returns the key pair TLV template used by the LOAD KEY command.
+func (kp *KeyPair) Serialize() []byte {
+	tpl := new(bytes.Buffer)
+	writeTag(tpl, TagKeyPairPublicKey, kp.PublicKey)
+	writeTag(tpl, TagKeyPairPrivateKey, kp.PrivateKey)
+	writeTag(tpl, TagKeyPairChainCode, kp.ChainCode)
+
+	buf := new(bytes.Buffer)
+	writeTag(buf, TagKeyPairTemplate, tpl.Bytes())
+
+	return buf.Bytes()
+}
+
+func writeTag(buf *bytes.Buffer, tag uint8, value []byte) {
+	if len(value) == 0 {
+		return
+	}
+
+	apdu.WriteTLV(buf, apdu.Tag{tag}, value)
+}
@@ -0,0 +1,35 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyPair_Serialize(t *testing.T) {
+	kp := &KeyPair{
+		PrivateKey: hexutils.HexToBytes("0102"),
+		PublicKey:  hexutils.HexToBytes("040506"),
+	}
+
+	assert.False(t, kp.IsExtended())
+	assert.Equal(t, "A1 09 80 03 04 05 06 81 02 01 02", hexutils.BytesToHexWithSpaces(kp.Serialize()))
+
+	kp.ChainCode = hexutils.HexToBytes("AABB")
+	assert.True(t, kp.IsExtended())
+	assert.Equal(t, "A1 0D 80 03 04 05 06 81 02 01 02 82 02 AA BB", hexutils.BytesToHexWithSpaces(kp.Serialize()))
+}
+
+func TestKeyPair_CompressedPublicKey(t *testing.T) {
+	kp := &KeyPair{
+		PublicKey: hexutils.HexToBytes("0479BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8"),
+	}
+
+	pub, err := kp.CompressedPublicKey()
+	assert.NoError(t, err)
+	assert.Equal(t, "0279BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", hexutils.BytesToHex(pub))
+
+	_, err = (&KeyPair{}).CompressedPublicKey()
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"encoding/hex"
+	"encoding/json"
+)
+
+// PairingInfo is the key and the slot index of a pairing with a card.
+// It must be persisted to open secure channels without pairing again.
+type PairingInfo struct {
+	Key   []byte
+	Index int
+}
+
+type pairingInfoJSON struct {
+	Key   string `json:"key"`
+	Index int    `json:"index"`
+}
+
+// MarshalJSON encodes the pairing with its key as a hex string.
+func (p *PairingInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&pairingInfoJSON{
+		Key:   hex.EncodeToString(p.Key),
+		Index: p.Index,
+	})
+}
+
+// UnmarshalJSON decodes a pairing encoded by MarshalJSON.
+func (p *PairingInfo) UnmarshalJSON(data []byte) error {
+	var v pairingInfoJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	key, err := hex.DecodeString(v.Key)
+	if err != nil {
+		return err
+	}
+
+	p.Key = key
+	p.Index = v.Index
+
+	return nil
+}
+
+// PairingSlots describes the usage of the pairing slots of a card.
+type PairingSlots struct {
+	Total int
+	// Free is the number of free slots, or -1 if the card didn't report it.
+	Free int
+	// Current is the slot of the pairing in use, or -1 if the client isn't paired.
+	Current int
+}
@@ -0,0 +1,23 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPairingInfo_JSON(t *testing.T) {
+	p := &PairingInfo{Key: []byte{0x01, 0xAB, 0xFF}, Index: 3}
+
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key":"01abff","index":3}`, string(data))
+
+	decoded := &PairingInfo{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, p, decoded)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"key":"zz","index":0}`), decoded))
+}
@@ -2,28 +2,42 @@ package types
 
 import (
 	"bytes"
+	"errors"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/status-im/keycard-go/apdu"
+	"github.com/status-im/keycard-go/ethutils"
 )
 
 var (
 	TagSignatureTemplate = uint8(0xA0)
 )
 
+var ErrInvalidSignature = errors.New("signature does not match the public key")
+
 type Signature struct {
 	pubKey []byte
 	r      []byte
 	s      []byte
 	v      byte
+	der    []byte
 }
 
+// ParseSignature parses the SIGN response template and computes the recovery id of the
+// signature by matching the key recovered from message against the returned public key.
+// resp is the data of the response without the status word, so responses received
+// out of band, like through a relay, can be parsed the same way as the ones of CommandSet.Sign.
 func ParseSignature(message, resp []byte) (*Signature, error) {
 	pubKey, err := apdu.FindTag(resp, apdu.Tag{TagSignatureTemplate}, apdu.Tag{0x80})
 	if err != nil {
 		return nil, err
 	}
 
+	derSeq, err := apdu.FindTag(resp, apdu.Tag{TagSignatureTemplate}, apdu.Tag{0x30})
+	if err != nil {
+		return nil, err
+	}
+
 	r, err := apdu.FindTagN(resp, 0, apdu.Tag{TagSignatureTemplate}, apdu.Tag{0x30}, apdu.Tag{0x02})
 	if err != nil {
 		return nil, err
@@ -42,6 +56,9 @@ func ParseSignature(message, resp []byte) (*Signature, error) {
 		s = s[len(s)-32:]
 	}
 
+	r = leftPad32(r)
+	s = leftPad32(s)
+
 	v, err := calculateV(message, pubKey, r, s)
 	if err != nil {
 		return nil, err
@@ -52,6 +69,7 @@ func ParseSignature(message, resp []byte) (*Signature, error) {
 		r:      r,
 		s:      s,
 		v:      v,
+		der:    encodeDER(derSeq),
 	}, nil
 }
 
@@ -71,10 +89,34 @@ func (s *Signature) V() byte {
 	return s.v
 }
 
-func calculateV(message, pubKey, r, s []byte) (v byte, err error) {
-	rs := append(r, s...)
+// DER returns the signature as returned by the card, a DER encoded ECDSA-Sig-Value sequence
+// of R and S as signed integers, for verifiers that don't take R and S.
+func (s *Signature) DER() []byte {
+	return s.der
+}
+
+// RecoverAddress recovers the public key that signed hash with R, S and V, and returns
+// its EIP-55 checksummed Ethereum address.
+func (s *Signature) RecoverAddress(hash []byte) (string, error) {
+	sig := make([]byte, 0, 65)
+	sig = append(sig, s.r...)
+	sig = append(sig, s.s...)
+	sig = append(sig, s.v)
+
+	pubKey, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		return "", err
+	}
+
+	return ethutils.EthereumAddress(pubKey)
+}
+
+func calculateV(message, pubKey, r, s []byte) (byte, error) {
+	rs := make([]byte, 0, 65)
+	rs = append(rs, r...)
+	rs = append(rs, s...)
 	for i := 0; i < 2; i++ {
-		v = byte(i)
+		v := byte(i)
 		sig := append(rs, v)
 		rec, err := crypto.Ecrecover(message, sig)
 		if err != nil {
@@ -86,5 +128,24 @@ func calculateV(message, pubKey, r, s []byte) (v byte, err error) {
 		}
 	}
 
-	return v, err
+	return 0, ErrInvalidSignature
+}
+
+// encodeDER returns the DER sequence with the value seq.
+func encodeDER(seq []byte) []byte {
+	buf := new(bytes.Buffer)
+	apdu.WriteTLV(buf, apdu.Tag{0x30}, seq)
+
+	return buf.Bytes()
+}
+
+func leftPad32(b []byte) []byte {
+	if len(b) >= 32 {
+		return b
+	}
+
+	padded := make([]byte, 32)
+	copy(padded[32-len(b):], b)
+
+	return padded
 }
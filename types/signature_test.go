@@ -0,0 +1,65 @@
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signatureResponse(pubKey, r, s []byte) []byte {
+	der := new(bytes.Buffer)
+	writeTag(der, 0x02, r)
+	writeTag(der, 0x02, s)
+
+	tpl := new(bytes.Buffer)
+	writeTag(tpl, 0x80, pubKey)
+	writeTag(tpl, 0x30, der.Bytes())
+
+	buf := new(bytes.Buffer)
+	writeTag(buf, TagSignatureTemplate, tpl.Bytes())
+
+	return buf.Bytes()
+}
+
+func TestParseSignature(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	pubKey := crypto.FromECDSAPub(&key.PublicKey)
+
+	hash := crypto.Keccak256([]byte("keycard"))
+	sig, err := crypto.Sign(hash, key)
+	require.NoError(t, err)
+
+	// DER integers are signed, so a high bit requires a leading zero byte.
+	r := append([]byte{0x00}, sig[:32]...)
+	s := bytes.TrimLeft(sig[32:64], "\x00")
+
+	parsed, err := ParseSignature(hash, signatureResponse(pubKey, r, s))
+	require.NoError(t, err)
+	assert.Equal(t, pubKey, parsed.PubKey())
+	assert.Equal(t, sig[:32], parsed.R())
+	assert.Equal(t, sig[32:64], parsed.S())
+	assert.Equal(t, sig[64], parsed.V())
+
+	// the DER signature is the one in the response, with the leading zero of R
+	der := append([]byte{0x30, byte(4 + len(r) + len(s))}, append([]byte{0x02, byte(len(r))}, r...)...)
+	der = append(der, append([]byte{0x02, byte(len(s))}, s...)...)
+	assert.Equal(t, der, parsed.DER())
+	assert.Equal(t, r, parsed.DER()[4:4+len(r)])
+
+	address, err := parsed.RecoverAddress(hash)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey).Hex(), address)
+
+	address, err = parsed.RecoverAddress(crypto.Keccak256([]byte("other")))
+	require.NoError(t, err)
+	assert.NotEqual(t, crypto.PubkeyToAddress(key.PublicKey).Hex(), address)
+
+	otherKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	_, err = ParseSignature(hash, signatureResponse(crypto.FromECDSAPub(&otherKey.PublicKey), r, s))
+	assert.Equal(t, ErrInvalidSignature, err)
+}
@@ -0,0 +1,30 @@
+package types
+
+import (
+	"math/big"
+)
+
+// TxSignature is a transaction signature with the EIP-155 replay protected V.
+type TxSignature struct {
+	*Signature
+	ChainID *big.Int
+}
+
+// NewTxSignature wraps sig for a transaction on chainID.
+func NewTxSignature(sig *Signature, chainID *big.Int) *TxSignature {
+	return &TxSignature{
+		Signature: sig,
+		ChainID:   new(big.Int).Set(chainID),
+	}
+}
+
+// V returns chainID * 2 + 35 + the recovery id.
+func (s *TxSignature) V() *big.Int {
+	v := new(big.Int).Mul(s.ChainID, big.NewInt(2))
+	return v.Add(v, big.NewInt(35+int64(s.Signature.V())))
+}
+
+// RecoveryID returns the recovery id of the signature, 0 or 1.
+func (s *TxSignature) RecoveryID() byte {
+	return s.Signature.V()
+}
@@ -0,0 +1,17 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxSignature_V(t *testing.T) {
+	sig := NewTxSignature(&Signature{v: 1}, big.NewInt(1))
+	assert.Equal(t, big.NewInt(38), sig.V())
+	assert.Equal(t, byte(1), sig.RecoveryID())
+
+	sig = NewTxSignature(&Signature{v: 0}, big.NewInt(1337))
+	assert.Equal(t, big.NewInt(2709), sig.V())
+}
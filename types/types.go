@@ -6,8 +6,3 @@ import "github.com/status-im/keycard-go/apdu"
 type Channel interface {
 	Send(*apdu.Command) (*apdu.Response, error)
 }
-
-type PairingInfo struct {
-	Key   []byte
-	Index int
-}
\ No newline at end of file